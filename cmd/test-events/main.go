@@ -0,0 +1,59 @@
+// Command test-events checks an EventBridge event pattern against a directory
+// of sample events without calling AWS, for use in CI.
+//
+//	test-events -pattern pattern.json -events events/
+//
+// Events under events/match must match the pattern and events under
+// events/no_match must not.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/phoban01/lambda-ext/eventpattern"
+)
+
+func main() {
+	patternFile := flag.String("pattern", "", "path to the JSON event pattern")
+	eventsDir := flag.String("events", "", "directory of sample events")
+	flag.Parse()
+
+	if *patternFile == "" || *eventsDir == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := ioutil.ReadFile(*patternFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	p, err := eventpattern.Parse(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid pattern %s: %s\n", *patternFile, err)
+		os.Exit(1)
+	}
+
+	results, err := eventpattern.Verify(p, *eventsDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	failed := 0
+	for _, r := range results {
+		fmt.Println(r)
+		if !r.Passed() {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d of %d events failed\n", failed, len(results))
+		os.Exit(1)
+	}
+}
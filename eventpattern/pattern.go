@@ -0,0 +1,503 @@
+// Package eventpattern implements EventBridge event pattern validation and
+// matching, so that patterns can be checked against sample events without
+// calling AWS.
+package eventpattern
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Pattern is a parsed and validated EventBridge event pattern.
+type Pattern struct {
+	root map[string]interface{}
+}
+
+// The comparisons allowed in a numeric filter.
+var numericOperators = map[string]bool{
+	"<": true, "<=": true, "=": true, ">": true, ">=": true,
+}
+
+// Parse validates data as an event pattern EventBridge accepts: a non-empty
+// object whose leaves are arrays of values or content filters.
+func Parse(data []byte) (*Pattern, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("pattern must be a JSON object: %s", err)
+	}
+
+	if err := validateObject(root, ""); err != nil {
+		return nil, err
+	}
+
+	return &Pattern{root: root}, nil
+}
+
+// Match reports whether the JSON event matches the pattern.
+func (p *Pattern) Match(event []byte) (bool, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(event, &doc); err != nil {
+		return false, fmt.Errorf("event must be a JSON object: %s", err)
+	}
+
+	return matchObject(p.root, doc, true), nil
+}
+
+// matchObject matches every field of the pattern against obj. present is
+// false when obj itself is missing from the event.
+func matchObject(pattern map[string]interface{}, obj map[string]interface{}, present bool) bool {
+	for k, want := range pattern {
+		v, ok := obj[k]
+		if !present {
+			ok = false
+		}
+
+		switch want := want.(type) {
+		case map[string]interface{}:
+			// An array of objects matches if any of its objects does
+			if arr, isArr := v.([]interface{}); ok && isArr {
+				if !matchAny(want, arr) {
+					return false
+				}
+				continue
+			}
+
+			nested, isObj := v.(map[string]interface{})
+			if ok && !isObj {
+				return false
+			}
+			if !matchObject(want, nested, ok) {
+				return false
+			}
+
+		case []interface{}:
+			if !matchField(want, v, ok) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// matchAny reports whether any object in arr matches the pattern.
+func matchAny(pattern map[string]interface{}, arr []interface{}) bool {
+	for _, el := range arr {
+		if obj, ok := el.(map[string]interface{}); ok && matchObject(pattern, obj, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchField reports whether any of the matchers accepts the field. Arrays
+// in the event match if any of their elements does.
+func matchField(matchers []interface{}, v interface{}, present bool) bool {
+	values := []interface{}{v}
+	if arr, ok := v.([]interface{}); ok {
+		values = arr
+	}
+
+	for _, m := range matchers {
+		filter, isFilter := m.(map[string]interface{})
+
+		// exists is about the field rather than any of its values
+		if isFilter {
+			if exists, ok := filter["exists"].(bool); ok {
+				if exists == (present && !isObject(v)) {
+					return true
+				}
+				continue
+			}
+		}
+
+		if !present {
+			continue
+		}
+
+		for _, value := range values {
+			if isFilter {
+				if matchFilter(filter, value) {
+					return true
+				}
+			} else if equal(m, value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchFilter(filter map[string]interface{}, v interface{}) bool {
+	for op, arg := range filter {
+		switch op {
+		case "prefix", "suffix", "equals-ignore-case", "wildcard":
+			s, ok := v.(string)
+			return ok && matchString(op, arg, s)
+
+		case "cidr":
+			s, ok := v.(string)
+			if !ok {
+				return false
+			}
+			_, ipnet, err := net.ParseCIDR(arg.(string))
+			ip := net.ParseIP(s)
+			return err == nil && ip != nil && ipnet.Contains(ip)
+
+		case "numeric":
+			n, ok := v.(float64)
+			if !ok {
+				return false
+			}
+			pairs := arg.([]interface{})
+			for i := 0; i < len(pairs); i += 2 {
+				if !compare(n, pairs[i].(string), pairs[i+1].(float64)) {
+					return false
+				}
+			}
+			return true
+
+		case "anything-but":
+			switch a := arg.(type) {
+			case []interface{}:
+				for _, excluded := range a {
+					if equal(excluded, v) {
+						return false
+					}
+				}
+				return isScalar(v)
+			case map[string]interface{}:
+				s, ok := v.(string)
+				if !ok {
+					return false
+				}
+				for nested, narg := range a {
+					// equals-ignore-case and wildcard may exclude a list
+					excluded, isList := narg.([]interface{})
+					if !isList {
+						excluded = []interface{}{narg}
+					}
+					for _, e := range excluded {
+						if matchString(nested, e, s) {
+							return false
+						}
+					}
+				}
+				return true
+			default:
+				return isScalar(v) && !equal(a, v)
+			}
+		}
+	}
+
+	return false
+}
+
+// matchString applies a string filter to s. prefix and suffix take either a
+// string or an equals-ignore-case filter.
+func matchString(op string, arg interface{}, s string) bool {
+	want, ok := arg.(string)
+	fold := false
+	if !ok {
+		want, fold = arg.(map[string]interface{})["equals-ignore-case"].(string), true
+	}
+
+	switch op {
+	case "prefix":
+		return len(s) >= len(want) && equalString(s[:len(want)], want, fold)
+	case "suffix":
+		return len(s) >= len(want) && equalString(s[len(s)-len(want):], want, fold)
+	case "equals-ignore-case":
+		return strings.EqualFold(s, want)
+	case "wildcard":
+		return wildcard(want).MatchString(s)
+	}
+	return false
+}
+
+func equalString(a, b string, fold bool) bool {
+	if fold {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// wildcard compiles a wildcard filter, in which * matches any run of
+// characters and \* a literal star.
+func wildcard(pattern string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("^(?s)")
+
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			i++
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case pattern[i] == '*':
+			expr.WriteString(".*")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+func compare(n float64, op string, bound float64) bool {
+	switch op {
+	case "<":
+		return n < bound
+	case "<=":
+		return n <= bound
+	case "=":
+		return n == bound
+	case ">":
+		return n > bound
+	case ">=":
+		return n >= bound
+	}
+	return false
+}
+
+// equal compares a pattern value with an event value. Numbers compare
+// numerically, and null only matches null.
+func equal(want, v interface{}) bool {
+	switch want := want.(type) {
+	case nil:
+		return v == nil
+	case string:
+		s, ok := v.(string)
+		return ok && s == want
+	case float64:
+		n, ok := v.(float64)
+		return ok && n == want
+	case bool:
+		b, ok := v.(bool)
+		return ok && b == want
+	}
+	return false
+}
+
+func isObject(v interface{}) bool {
+	_, ok := v.(map[string]interface{})
+	return ok
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, float64, bool:
+		return true
+	}
+	return false
+}
+
+func validateObject(obj map[string]interface{}, path string) error {
+	if len(obj) == 0 {
+		if path == "" {
+			return fmt.Errorf("pattern must not be empty")
+		}
+		return fmt.Errorf("%s: must not be empty", path)
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+
+		switch v := obj[k].(type) {
+		case map[string]interface{}:
+			if err := validateObject(v, p); err != nil {
+				return err
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				return fmt.Errorf("%s: must match at least one value", p)
+			}
+
+			for _, m := range v {
+				if err := validateMatcher(m, p); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("%s: must be an object or an array of values", p)
+		}
+	}
+
+	return nil
+}
+
+func validateMatcher(m interface{}, path string) error {
+	filter, ok := m.(map[string]interface{})
+	if !ok {
+		switch m.(type) {
+		case string, float64, bool, nil:
+			return nil
+		}
+		return fmt.Errorf("%s: arrays can't be nested", path)
+	}
+
+	if len(filter) != 1 {
+		return fmt.Errorf("%s: a content filter must have exactly one key", path)
+	}
+
+	for op, arg := range filter {
+		switch op {
+		case "prefix", "suffix":
+			if err := validateAffix(op, arg, path); err != nil {
+				return err
+			}
+
+		case "equals-ignore-case":
+			if _, ok := arg.(string); !ok {
+				return fmt.Errorf("%s: equals-ignore-case takes a string", path)
+			}
+
+		case "wildcard":
+			if err := validateWildcard(arg, path); err != nil {
+				return err
+			}
+
+		case "cidr":
+			s, ok := arg.(string)
+			if !ok {
+				return fmt.Errorf("%s: cidr takes a string", path)
+			}
+			if _, _, err := net.ParseCIDR(s); err != nil {
+				return fmt.Errorf("%s: invalid cidr %q", path, s)
+			}
+
+		case "exists":
+			if _, ok := arg.(bool); !ok {
+				return fmt.Errorf("%s: exists takes true or false", path)
+			}
+
+		case "numeric":
+			if err := validateNumeric(arg, path); err != nil {
+				return err
+			}
+
+		case "anything-but":
+			switch a := arg.(type) {
+			case string, float64:
+			case []interface{}:
+				for _, v := range a {
+					switch v.(type) {
+					case string, float64:
+					default:
+						return fmt.Errorf("%s: anything-but values must be strings or numbers", path)
+					}
+				}
+			case map[string]interface{}:
+				if err := validateAnythingBut(a, path); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("%s: anything-but takes a value, a list or a string filter", path)
+			}
+
+		default:
+			return fmt.Errorf("%s: unknown content filter %q", path, op)
+		}
+	}
+
+	return nil
+}
+
+// validateAffix checks a prefix or suffix filter, which takes a string or an
+// equals-ignore-case filter.
+func validateAffix(op string, arg interface{}, path string) error {
+	switch a := arg.(type) {
+	case string:
+		return nil
+	case map[string]interface{}:
+		if _, ok := a["equals-ignore-case"].(string); ok && len(a) == 1 {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: %s takes a string or an equals-ignore-case filter", path, op)
+}
+
+func validateWildcard(arg interface{}, path string) error {
+	s, ok := arg.(string)
+	if !ok {
+		return fmt.Errorf("%s: wildcard takes a string", path)
+	}
+	if strings.Contains(s, "**") {
+		return fmt.Errorf("%s: wildcard %q has consecutive wildcards", path, s)
+	}
+	return nil
+}
+
+// validateAnythingBut checks the string filter nested in an anything-but.
+// prefix and suffix take a string; equals-ignore-case and wildcard take a
+// string or a list of strings.
+func validateAnythingBut(filter map[string]interface{}, path string) error {
+	if len(filter) != 1 {
+		return fmt.Errorf("%s: anything-but nests exactly one string filter", path)
+	}
+
+	for op, arg := range filter {
+		switch op {
+		case "prefix", "suffix":
+			if _, ok := arg.(string); !ok {
+				return fmt.Errorf("%s: anything-but %s takes a string", path, op)
+			}
+		case "equals-ignore-case", "wildcard":
+			values, isList := arg.([]interface{})
+			if !isList {
+				values = []interface{}{arg}
+			}
+			if len(values) == 0 {
+				return fmt.Errorf("%s: anything-but %s must exclude at least one value", path, op)
+			}
+			for _, v := range values {
+				if _, ok := v.(string); !ok {
+					return fmt.Errorf("%s: anything-but %s takes strings", path, op)
+				}
+				if op == "wildcard" {
+					if err := validateWildcard(v, path); err != nil {
+						return err
+					}
+				}
+			}
+		default:
+			return fmt.Errorf("%s: anything-but can't nest %q", path, op)
+		}
+	}
+
+	return nil
+}
+
+func validateNumeric(arg interface{}, path string) error {
+	pairs, ok := arg.([]interface{})
+	if !ok || len(pairs) == 0 || len(pairs)%2 != 0 || len(pairs) > 4 {
+		return fmt.Errorf("%s: numeric takes one or two operator and value pairs", path)
+	}
+
+	for i := 0; i < len(pairs); i += 2 {
+		op, ok := pairs[i].(string)
+		if !ok || !numericOperators[op] {
+			return fmt.Errorf("%s: numeric has unknown operator %v", path, pairs[i])
+		}
+
+		if _, ok := pairs[i+1].(float64); !ok {
+			return fmt.Errorf("%s: numeric value %v is not a number", path, pairs[i+1])
+		}
+	}
+
+	return nil
+}
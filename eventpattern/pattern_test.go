@@ -0,0 +1,136 @@
+package eventpattern
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		name    string
+		pattern string
+		event   string
+		want    bool
+	}{
+		{"value", `{"source":["aws.s3"]}`, `{"source":"aws.s3"}`, true},
+		{"other value", `{"source":["aws.s3"]}`, `{"source":"aws.ec2"}`, false},
+		{"missing field", `{"source":["aws.s3"]}`, `{}`, false},
+
+		{"prefix", `{"key":[{"prefix":"incoming/"}]}`, `{"key":"incoming/a.csv"}`, true},
+		{"prefix mismatch", `{"key":[{"prefix":"incoming/"}]}`, `{"key":"outgoing/a.csv"}`, false},
+		{"prefix number", `{"key":[{"prefix":"1"}]}`, `{"key":12}`, false},
+		{"prefix ignoring case", `{"key":[{"prefix":{"equals-ignore-case":"IN"}}]}`, `{"key":"incoming"}`, true},
+		{"suffix", `{"key":[{"suffix":".csv"}]}`, `{"key":"incoming/a.csv"}`, true},
+		{"suffix mismatch", `{"key":[{"suffix":".csv"}]}`, `{"key":"incoming/a.json"}`, false},
+		{"suffix ignoring case", `{"key":[{"suffix":{"equals-ignore-case":".CSV"}}]}`, `{"key":"a.csv"}`, true},
+		{"suffix number", `{"key":[{"suffix":"2"}]}`, `{"key":12}`, false},
+		{"equals ignoring case", `{"state":[{"equals-ignore-case":"RUNNING"}]}`, `{"state":"running"}`, true},
+		{"equals ignoring case mismatch", `{"state":[{"equals-ignore-case":"RUNNING"}]}`, `{"state":"runs"}`, false},
+		{"wildcard", `{"key":[{"wildcard":"dir/*.png"}]}`, `{"key":"dir/sub/a.png"}`, true},
+		{"wildcard mismatch", `{"key":[{"wildcard":"dir/*.png"}]}`, `{"key":"dir/a.jpg"}`, false},
+		{"wildcard escaped star", `{"key":[{"wildcard":"a\\*b"}]}`, `{"key":"axb"}`, false},
+		{"wildcard literal star", `{"key":[{"wildcard":"a\\*b"}]}`, `{"key":"a*b"}`, true},
+		{"wildcard whole value", `{"key":[{"wildcard":"a*"}]}`, `{"key":"ba"}`, false},
+
+		{"anything-but value", `{"state":[{"anything-but":"stopped"}]}`, `{"state":"running"}`, true},
+		{"anything-but excluded", `{"state":[{"anything-but":"stopped"}]}`, `{"state":"stopped"}`, false},
+		{"anything-but list", `{"state":[{"anything-but":["stopped","pending"]}]}`, `{"state":"pending"}`, false},
+		{"anything-but number", `{"code":[{"anything-but":[404]}]}`, `{"code":200}`, true},
+		{"anything-but prefix", `{"key":[{"anything-but":{"prefix":"tmp/"}}]}`, `{"key":"tmp/a"}`, false},
+		{"anything-but suffix", `{"key":[{"anything-but":{"suffix":".tmp"}}]}`, `{"key":"a.csv"}`, true},
+		{"anything-but ignoring case", `{"state":[{"anything-but":{"equals-ignore-case":["STOPPED"]}}]}`, `{"state":"stopped"}`, false},
+		{"anything-but wildcard", `{"key":[{"anything-but":{"wildcard":"*.tmp"}}]}`, `{"key":"a.csv"}`, true},
+		{"anything-but wildcard excluded", `{"key":[{"anything-but":{"wildcard":["*.tmp","*.bak"]}}]}`, `{"key":"a.bak"}`, false},
+		{"anything-but missing", `{"state":[{"anything-but":"stopped"}]}`, `{}`, false},
+
+		{"numeric range", `{"size":[{"numeric":[">",0,"<=",100]}]}`, `{"size":100}`, true},
+		{"numeric out of range", `{"size":[{"numeric":[">",0,"<=",100]}]}`, `{"size":101}`, false},
+		{"numeric equal", `{"size":[{"numeric":["=",3.5]}]}`, `{"size":3.5}`, true},
+		{"numeric string", `{"size":[{"numeric":[">",0]}]}`, `{"size":"5"}`, false},
+
+		{"exists", `{"error":[{"exists":true}]}`, `{"error":"timeout"}`, true},
+		{"exists missing", `{"error":[{"exists":true}]}`, `{}`, false},
+		{"exists false", `{"error":[{"exists":false}]}`, `{}`, true},
+		{"exists false present", `{"error":[{"exists":false}]}`, `{"error":null}`, false},
+		{"exists object", `{"detail":[{"exists":true}]}`, `{"detail":{"a":1}}`, false},
+
+		{"cidr", `{"ip":[{"cidr":"10.0.0.0/24"}]}`, `{"ip":"10.0.0.42"}`, true},
+		{"cidr outside", `{"ip":[{"cidr":"10.0.0.0/24"}]}`, `{"ip":"10.0.1.42"}`, false},
+		{"cidr not an address", `{"ip":[{"cidr":"10.0.0.0/24"}]}`, `{"ip":"host"}`, false},
+
+		{"scalar against array", `{"tags":["prod"]}`, `{"tags":["dev","prod"]}`, true},
+		{"scalar against array mismatch", `{"tags":["prod"]}`, `{"tags":["dev","test"]}`, false},
+		{"filter against array", `{"tags":[{"prefix":"pr"}]}`, `{"tags":["dev","prod"]}`, true},
+
+		{"nested", `{"detail":{"state":["running"]}}`, `{"detail":{"state":"running"}}`, true},
+		{"nested missing", `{"detail":{"state":["running"]}}`, `{"source":"aws.ec2"}`, false},
+		{"nested not an object", `{"detail":{"state":["running"]}}`, `{"detail":"running"}`, false},
+		{"nested exists false", `{"detail":{"error":[{"exists":false}]}}`, `{}`, true},
+		{"array of objects", `{"detail":{"items":{"id":[1]}}}`, `{"detail":{"items":[{"id":1},{"id":2}]}}`, true},
+		{"array of objects mismatch", `{"detail":{"items":{"id":[3]}}}`, `{"detail":{"items":[{"id":1},{"id":2}]}}`, false},
+		{"array of scalars", `{"detail":{"items":{"id":[1]}}}`, `{"detail":{"items":[1,2]}}`, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p, err := Parse([]byte(c.pattern))
+			if err != nil {
+				t.Fatalf("Parse(%s): %s", c.pattern, err)
+			}
+
+			got, err := p.Match([]byte(c.event))
+			if err != nil {
+				t.Fatalf("Match(%s): %s", c.event, err)
+			}
+
+			if got != c.want {
+				t.Errorf("pattern %s against %s = %v, want %v", c.pattern, c.event, got, c.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name    string
+		pattern string
+		valid   bool
+	}{
+		{"values", `{"source":["aws.s3"],"detail":{"state":["running",null,1,true]}}`, true},
+		{"suffix ignoring case", `{"key":[{"suffix":{"equals-ignore-case":".CSV"}}]}`, true},
+		{"wildcard", `{"key":[{"wildcard":"*.png"}]}`, true},
+		{"anything-but wildcard list", `{"key":[{"anything-but":{"wildcard":["*.tmp","*.bak"]}}]}`, true},
+
+		{"not an object", `["aws.s3"]`, false},
+		{"empty", `{}`, false},
+		{"empty nested", `{"detail":{}}`, false},
+		{"scalar leaf", `{"source":"aws.s3"}`, false},
+		{"empty array", `{"source":[]}`, false},
+		{"nested array", `{"source":[["aws.s3"]]}`, false},
+		{"two filter keys", `{"key":[{"prefix":"a","suffix":"b"}]}`, false},
+		{"unknown filter", `{"key":[{"contains":"a"}]}`, false},
+		{"prefix number", `{"key":[{"prefix":1}]}`, false},
+		{"consecutive wildcards", `{"key":[{"wildcard":"a**b"}]}`, false},
+		{"suffix number", `{"key":[{"suffix":1}]}`, false},
+		{"equals ignoring case number", `{"key":[{"equals-ignore-case":1}]}`, false},
+		{"wildcard list", `{"key":[{"wildcard":["*.png"]}]}`, false},
+		{"prefix other filter", `{"key":[{"prefix":{"wildcard":"a*"}}]}`, false},
+		{"invalid cidr", `{"ip":[{"cidr":"10.0.0.0/33"}]}`, false},
+		{"exists string", `{"key":[{"exists":"true"}]}`, false},
+		{"numeric operator", `{"n":[{"numeric":["!=",1]}]}`, false},
+		{"numeric odd", `{"n":[{"numeric":[">",1,"<"]}]}`, false},
+		{"numeric string value", `{"n":[{"numeric":[">","1"]}]}`, false},
+		{"anything-but bool", `{"key":[{"anything-but":true}]}`, false},
+		{"anything-but numeric", `{"key":[{"anything-but":{"numeric":[">",1]}}]}`, false},
+		{"anything-but two filters", `{"key":[{"anything-but":{"prefix":"a","suffix":"b"}}]}`, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Parse([]byte(c.pattern))
+			if c.valid && err != nil {
+				t.Errorf("Parse(%s): %s", c.pattern, err)
+			}
+			if !c.valid && err == nil {
+				t.Errorf("Parse(%s) succeeded, want an error", c.pattern)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package eventpattern
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// The subdirectories of a sample directory holding the events a pattern must
+// and must not match.
+const (
+	MatchDir   = "match"
+	NoMatchDir = "no_match"
+)
+
+// Result is the outcome of matching one sample event.
+type Result struct {
+	// File is the path of the event relative to the sample directory.
+	File string
+
+	// Event is the content of the file.
+	Event []byte
+
+	Expected bool
+	Matched  bool
+
+	// Err is set if the event couldn't be read or parsed.
+	Err error
+}
+
+// Passed reports whether the event matched as expected.
+func (r *Result) Passed() bool {
+	return r.Err == nil && r.Matched == r.Expected
+}
+
+func (r *Result) String() string {
+	expect := "match"
+	if !r.Expected {
+		expect = "no match"
+	}
+
+	switch {
+	case r.Err != nil:
+		return fmt.Sprintf("%s: %s", r.File, r.Err)
+	case r.Passed():
+		return fmt.Sprintf("%s: ok (%s)", r.File, expect)
+	default:
+		return fmt.Sprintf("%s: expected %s", r.File, expect)
+	}
+}
+
+// Verify matches every JSON event in the match and no_match subdirectories of
+// dir against the pattern, returning one result per event in file order.
+func Verify(p *Pattern, dir string) ([]*Result, error) {
+	var results []*Result
+
+	for _, sub := range []string{MatchDir, NoMatchDir} {
+		files, err := filepath.Glob(filepath.Join(dir, sub, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+
+		for _, f := range files {
+			r := &Result{
+				File:     filepath.Join(sub, filepath.Base(f)),
+				Expected: sub == MatchDir,
+			}
+
+			data, err := ioutil.ReadFile(f)
+			if err == nil {
+				r.Event = data
+				r.Matched, err = p.Match(data)
+			}
+			r.Err = err
+
+			results = append(results, r)
+		}
+	}
+
+	if len(results) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("no sample events in %s or %s under %s", MatchDir, NoMatchDir, dir)
+	}
+
+	return results, nil
+}
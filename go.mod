@@ -3,10 +3,14 @@ module github.com/phoban01/lambda-ext
 go 1.14
 
 require (
-	github.com/aws/aws-sdk-go v1.38.36
+	github.com/aws/aws-sdk-go v1.44.180
 	github.com/golang/protobuf v1.4.3
 	github.com/hashicorp/go-hclog v0.14.1
 	github.com/hashicorp/waypoint v0.3.1-0.20210510173902-9d588746be04
-	github.com/hashicorp/waypoint-plugin-sdk v0.0.0-20210420153757-b55c787a65ff
+	github.com/hashicorp/waypoint-plugin-sdk v0.0.0-20210526204726-417a273d6412
 	github.com/pkg/errors v0.9.1
 )
+
+// gortc.io no longer serves its vanity import path, so the module is fetched
+// from its GitHub mirror
+replace gortc.io/stun => github.com/gortc/stun v1.22.2
@@ -3,10 +3,13 @@ package platform
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
@@ -30,6 +33,40 @@ type DeployConfig struct {
 	SecurityGroupIds  []*string `hcl:"security_group_ids,optional"`
 	EfsAccessPointArn *string   `hcl:"efs_access_point_arn,optional"`
 	EfsMountPath      *string   `hcl:"efs_mount_path,optional"`
+
+	// Environment variables for the function, merged over ENV and the
+	// Waypoint entrypoint variables. Values may reference "ssm:/path" or
+	// "secretsmanager:<arn>#<key>".
+	Environment map[string]string `hcl:"environment,optional"`
+
+	// Secrets is either "resolve" (the default) to look references up at
+	// deploy time, or "reference" to pass the parameter name or secret ARN
+	// through for the function to look up at runtime.
+	Secrets string `hcl:"secrets,optional"`
+
+	// TracingMode is the X-Ray tracing mode, "Active" or "PassThrough".
+	TracingMode string `hcl:"tracing_mode,optional"`
+
+	// PolicyStatements are added as an inline policy to the execution role
+	// the plugin manages when role_arn is not set.
+	PolicyStatements []*PolicyStatement `hcl:"policy_statement,block"`
+
+	// KeepVersions is how many of the newest published versions to keep.
+	// Older versions are deleted unless something still references them.
+	// Zero keeps every version.
+	KeepVersions int `hcl:"keep_versions,optional"`
+
+	// WaitTimeout bounds how long to wait for the function to become
+	// active after each change, such as "5m".
+	WaitTimeout string `hcl:"wait_timeout,optional"`
+
+	// SmokeTest invokes each newly published version and deletes it again
+	// if the invocation doesn't meet expectations.
+	SmokeTest *SmokeTestConfig `hcl:"smoke_test,block"`
+
+	// PlanOnly shows how a deploy would change the function, and fails it
+	// without making any changes.
+	PlanOnly bool `hcl:"plan_only,optional"`
 }
 
 type Platform struct {
@@ -54,6 +91,40 @@ func (p *Platform) ConfigSet(config interface{}) error {
 		c.Region = "eu-west-1"
 	}
 
+	switch c.TracingMode {
+	case "", lambda.TracingModeActive, lambda.TracingModePassThrough:
+	default:
+		return fmt.Errorf("tracing_mode must be %q or %q", lambda.TracingModeActive, lambda.TracingModePassThrough)
+	}
+
+	if c.RoleArn != "" && len(c.PolicyStatements) > 0 {
+		return fmt.Errorf("policy_statement can only be used with the managed role, remove role_arn")
+	}
+
+	if c.WaitTimeout != "" {
+		if _, err := time.ParseDuration(c.WaitTimeout); err != nil {
+			return fmt.Errorf("invalid wait_timeout %q: %s", c.WaitTimeout, err)
+		}
+	}
+
+	if c.KeepVersions < 0 {
+		return fmt.Errorf("keep_versions must not be negative")
+	}
+
+	if c.SmokeTest != nil {
+		if err := c.SmokeTest.validate(); err != nil {
+			return err
+		}
+	}
+
+	switch c.Secrets {
+	case "":
+		c.Secrets = SecretsResolve
+	case SecretsResolve, SecretsReference:
+	default:
+		return fmt.Errorf("secrets must be %q or %q", SecretsResolve, SecretsReference)
+	}
+
 	return nil
 }
 
@@ -74,6 +145,10 @@ const (
 	DefaultEnv = "dev"
 )
 
+// The description prefix of versions published for a Waypoint deployment,
+// followed by the deployment ID.
+const deploymentDescription = "waypoint deployment "
+
 func (p *Platform) deploy(
 	ctx context.Context,
 	log hclog.Logger,
@@ -109,31 +184,41 @@ func (p *Platform) deploy(
 		return nil, err
 	}
 
-	roleArn := p.config.RoleArn
-
-	mem := int64(p.config.Memory)
-	if mem == 0 {
-		mem = DefaultMemory
+	vars, err := p.environment(ctx, sess, job, deployConfig)
+	if err != nil {
+		return nil, err
 	}
 
-	timeout := int64(p.config.Timeout)
-	if timeout == 0 {
-		timeout = DefaultTimeout
+	step.Done()
+
+	if p.config.PlanOnly {
+		return nil, p.plan(ctx, sg, ui, sess, src, job, img, vars)
 	}
 
-	env := job.Workspace
-	if env == "default" {
-		env = DefaultEnv
+	roleArn := p.config.RoleArn
+	if roleArn == "" {
+		step = sg.Add("Preparing IAM execution role")
+
+		roleArn, err = p.ensureRole(ctx, iam.New(sess), src, job)
+		if err != nil {
+			return nil, err
+		}
+
+		step.Update("Using IAM execution role: %s", roleArn)
+		step.Done()
 	}
 
-	step.Done()
+	spec := p.spec(roleArn, vars)
 
 	step = sg.Add("Reading Lambda function: %s", src.App)
 
 	lamSvc := lambda.New(sess)
-	curFunc, err := lamSvc.GetFunction(&lambda.GetFunctionInput{
+	_, err = lamSvc.GetFunctionWithContext(ctx, &lambda.GetFunctionInput{
 		FunctionName: aws.String(src.App),
 	})
+	if err != nil && !isCode(err, lambda.ErrCodeResourceNotFoundException) {
+		return nil, errors.Wrapf(err, "unable to read function %s", src.App)
+	}
 
 	var funcarn string
 
@@ -141,56 +226,33 @@ func (p *Platform) deploy(
 	if err == nil {
 		step.Update("Updating Lambda function with new code")
 
-		var reset bool
-		var update lambda.UpdateFunctionConfigurationInput
-
-		if *curFunc.Configuration.MemorySize != mem {
-			update.MemorySize = aws.Int64(mem)
-			reset = true
-		}
-
-		if *curFunc.Configuration.Timeout != timeout {
-			update.Timeout = aws.Int64(timeout)
-			reset = true
+		// A previous change may still be rolling out
+		cur, err := p.waitForFunction(ctx, lamSvc, src.App)
+		if err != nil {
+			return nil, err
 		}
 
-		if curFunc.Configuration.FileSystemConfigs[0].Arn != p.config.EfsAccessPointArn &&
-			curFunc.Configuration.FileSystemConfigs[0].LocalMountPath != p.config.EfsMountPath {
-			update.FileSystemConfigs = []*lambda.FileSystemConfig{
-				{
-					Arn:            p.config.EfsAccessPointArn,
-					LocalMountPath: p.config.EfsMountPath,
-				},
+		update, changes := spec.diff(cur)
+		if update != nil {
+			for _, c := range changes {
+				if c.changed {
+					log.Debug("updating function configuration", "field", c.field)
+				}
 			}
-			reset = true
-		}
-
-		if len(curFunc.Configuration.Environment.Variables) > 0 &&
-			*curFunc.Configuration.Environment.Variables["ENV"] != env {
-			update.Environment.Variables["ENV"] = aws.String(env)
-			reset = true
-		}
-
-		if !slicesEqual(curFunc.Configuration.VpcConfig.SubnetIds, p.config.SubnetIds) {
-			update.VpcConfig.SubnetIds = p.config.SubnetIds
-			reset = true
-		}
-
-		if !slicesEqual(curFunc.Configuration.VpcConfig.SecurityGroupIds, p.config.SecurityGroupIds) {
-			update.VpcConfig.SecurityGroupIds = p.config.SecurityGroupIds
-			reset = true
-		}
-
-		if reset {
-			update.FunctionName = curFunc.Configuration.FunctionArn
 
-			_, err = lamSvc.UpdateFunctionConfiguration(&update)
+			_, err = lamSvc.UpdateFunctionConfigurationWithContext(ctx, update)
 			if err != nil {
 				return nil, errors.Wrapf(err, "unable to update function configuration")
 			}
+
+			// The code can't be updated until the configuration update is done
+			_, err = p.waitForFunction(ctx, lamSvc, src.App)
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		funcCfg, err := lamSvc.UpdateFunctionCode(&lambda.UpdateFunctionCodeInput{
+		funcCfg, err := lamSvc.UpdateFunctionCodeWithContext(ctx, &lambda.UpdateFunctionCodeInput{
 			FunctionName: aws.String(src.App),
 			ImageUri:     aws.String(img.Name()),
 		})
@@ -201,23 +263,19 @@ func (p *Platform) deploy(
 
 		funcarn = *funcCfg.FunctionArn
 
-		if err != nil {
-			return nil, err
-		}
-
 		// We couldn't read the function before, so we'll go ahead and create one.
 	} else {
 		step.Update("Creating new Lambda function")
 
-		// Run this in a loop to guard against eventual consistency errors with the specified
-		// role not showing up within lambda right away.
-		for i := 0; i < 30; i++ {
-			funcOut, err := lamSvc.CreateFunction(&lambda.CreateFunctionInput{
+		// Retry to guard against eventual consistency errors with the specified
+		// role not being assumable by lambda right away.
+		err = poll(ctx, p.waitTimeout(), "the execution role to be assumable", func(ctx context.Context) error {
+			funcOut, err := lamSvc.CreateFunctionWithContext(ctx, &lambda.CreateFunctionInput{
 				Description:  aws.String(fmt.Sprintf("waypoint %s", src.App)),
 				FunctionName: aws.String(src.App),
-				Role:         aws.String(roleArn),
-				Timeout:      aws.Int64(timeout),
-				MemorySize:   aws.Int64(mem),
+				Role:         aws.String(spec.role),
+				Timeout:      aws.Int64(spec.timeout),
+				MemorySize:   aws.Int64(spec.memory),
 				Tags: map[string]*string{
 					"waypoint.app": aws.String(src.App),
 				},
@@ -225,84 +283,67 @@ func (p *Platform) deploy(
 				Code: &lambda.FunctionCode{
 					ImageUri: aws.String(img.Name()),
 				},
-				ImageConfig: &lambda.ImageConfig{},
-				VpcConfig: &lambda.VpcConfig{
-					SubnetIds:        p.config.SubnetIds,
-					SecurityGroupIds: p.config.SecurityGroupIds,
-				},
-				FileSystemConfigs: []*lambda.FileSystemConfig{
-					{
-						Arn:            p.config.EfsAccessPointArn,
-						LocalMountPath: p.config.EfsMountPath,
-					},
-				},
+				ImageConfig:       &lambda.ImageConfig{},
+				VpcConfig:         spec.vpcConfig(),
+				FileSystemConfigs: spec.fileSystemConfigs(),
 				Environment: &lambda.Environment{
-					Variables: map[string]*string{
-						"ENV": aws.String(env),
-					},
+					Variables: spec.environment,
+				},
+				TracingConfig: &lambda.TracingConfig{
+					Mode: aws.String(spec.tracingMode),
 				},
 			})
 
 			if err != nil {
-				// if we encounter an unrecoverable error, exit now.
-				if aerr, ok := err.(awserr.Error); ok {
-					switch aerr.Code() {
-					case "ResourceConflictException":
-						return nil, err
-					}
+				if aerr, ok := err.(awserr.Error); ok &&
+					aerr.Code() == lambda.ErrCodeInvalidParameterValueException &&
+					strings.Contains(aerr.Message(), "cannot be assumed") {
+					return errRetry
 				}
-
-				// otherwise sleep and try again
-				time.Sleep(2 * time.Second)
-			} else {
-				funcarn = *funcOut.FunctionArn
-				break
+				return err
 			}
-		}
-	}
 
-	if funcarn == "" {
-		return nil, fmt.Errorf("Unable to create function, timed out trying")
+			funcarn = *funcOut.FunctionArn
+			return nil
+		})
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to create function")
+		}
 	}
 
 	step.Done()
 
 	step = sg.Add("Waiting for Lambda function to be processed")
-	// The image is never ready right away, AWS has to process it, so we wait
-	// 3 seconds before trying to publish the version
 
-	time.Sleep(3 * time.Second)
+	// The image is never ready right away, AWS has to process it before a
+	// version can be published
+	_, err = p.waitForFunction(ctx, lamSvc, src.App)
+	if err != nil {
+		return nil, err
+	}
 
 	// no publish this new code to create a stable identifier for it. Otherwise
 	// if a manually pushes to the function and we use $LATEST, we'll accidentally
 	// start running their manual code rather then the fixed one we have here.
 	var ver *lambda.FunctionConfiguration
 
-	// Only try 30 times.
-	for i := 0; i < 30; i++ {
-		ver, err = lamSvc.PublishVersion(&lambda.PublishVersionInput{
+	err = poll(ctx, p.waitTimeout(), "the function version to be published", func(ctx context.Context) error {
+		ver, err = lamSvc.PublishVersionWithContext(ctx, &lambda.PublishVersionInput{
 			FunctionName: aws.String(src.App),
+			Description:  aws.String(deploymentDescription + id),
 		})
 
-		if err == nil {
-			break
+		// It's still updating, wait and try again
+		if isCode(err, lambda.ErrCodeResourceConflictException) {
+			return errRetry
 		}
 
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "ResourceConflictException":
-				// It's updating, wait a sec and try again
-				time.Sleep(time.Second)
-			default:
-				return nil, err
-			}
-		} else {
-			return nil, err
-		}
-	}
+		return err
+	})
 
-	if ver == nil {
-		return nil, fmt.Errorf("Lambda was unable to prepare the function in the aloted time")
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to publish function version")
 	}
 
 	verarn := *ver.FunctionArn
@@ -310,6 +351,52 @@ func (p *Platform) deploy(
 	step.Update("Published Lambda function: %s (%s)", verarn, *ver.Version)
 	step.Done()
 
+	if p.config.SmokeTest != nil {
+		step = sg.Add("Running smoke test against version %s", *ver.Version)
+
+		err = p.smokeTest(ctx, lamSvc, step, src, verarn)
+		if err != nil {
+			// When nothing changed, Lambda returns the latest version instead of
+			// publishing one. It keeps the description of the deployment that
+			// published it, and may be serving traffic, so it's left in place.
+			if aws.StringValue(ver.Description) != deploymentDescription+id {
+				return nil, errors.Wrapf(err, "smoke test failed against existing version %s", *ver.Version)
+			}
+
+			// Don't leave a broken version around for the release to pick up
+			_, derr := lamSvc.DeleteFunctionWithContext(ctx, &lambda.DeleteFunctionInput{
+				FunctionName: aws.String(src.App),
+				Qualifier:    ver.Version,
+			})
+			if derr != nil {
+				return nil, errors.Wrapf(err, "smoke test failed and version %s could not be deleted (%s)", *ver.Version, derr)
+			}
+
+			return nil, errors.Wrapf(err, "smoke test failed, deleted version %s", *ver.Version)
+		}
+
+		step.Update("Smoke test passed against version %s", *ver.Version)
+		step.Done()
+	}
+
+	if p.config.KeepVersions > 0 {
+		step = sg.Add("Pruning Lambda function versions beyond the newest %d", p.config.KeepVersions)
+
+		pruned, err := p.pruneVersions(ctx, lamSvc, eventbridge.New(sess), funcarn)
+		if err != nil {
+			// The new version is live, so a failed cleanup shouldn't fail the deploy
+			log.Warn("unable to prune function versions", "error", err)
+			step.Update("Unable to prune Lambda function versions: %s", err)
+			step.Status(terminal.StatusWarn)
+		} else if len(pruned) == 0 {
+			step.Update("No Lambda function versions to prune")
+		} else {
+			step.Update("Pruned Lambda function versions: %s", strings.Join(pruned, ", "))
+		}
+
+		step.Done()
+	}
+
 	deployment.Region = p.config.Region
 	deployment.Id = id
 	deployment.FuncArn = funcarn
@@ -346,17 +433,19 @@ func (p *Platform) destroy(ctx context.Context,
 	lamSvc := lambda.New(sess)
 
 	if deployment.Version != "" {
-		_, err = lamSvc.DeleteFunction(&lambda.DeleteFunctionInput{
+		_, err = lamSvc.DeleteFunctionWithContext(ctx, &lambda.DeleteFunctionInput{
 			FunctionName: aws.String(deployment.FuncArn),
 			Qualifier:    aws.String(deployment.Version),
 		})
-		if err != nil {
+
+		// keep_versions may already have pruned it
+		if err != nil && !isCode(err, lambda.ErrCodeResourceNotFoundException) {
 			return err
 		}
 	}
 	st.Step(terminal.StatusOK, "Deleted Lambda function version")
 
-	return err
+	return nil
 }
 
 func (p *Platform) DestroyWorkspaceFunc() interface{} {
@@ -367,6 +456,7 @@ func (p *Platform) destroyWorkspace(ctx context.Context,
 	log hclog.Logger,
 	ui terminal.UI,
 	src *component.Source,
+	job *component.JobInfo,
 ) error {
 	// We'll update the user in real time
 	st := ui.Status()
@@ -384,7 +474,7 @@ func (p *Platform) destroyWorkspace(ctx context.Context,
 
 	lamSvc := lambda.New(sess)
 
-	_, err = lamSvc.DeleteFunction(&lambda.DeleteFunctionInput{
+	_, err = lamSvc.DeleteFunctionWithContext(ctx, &lambda.DeleteFunctionInput{
 		FunctionName: aws.String(src.App),
 	})
 
@@ -394,15 +484,16 @@ func (p *Platform) destroyWorkspace(ctx context.Context,
 
 	st.Step(terminal.StatusOK, "Deleted Lambda function")
 
-	return nil
-}
+	if p.config.RoleArn == "" {
+		st.Update("Deleting IAM execution role")
 
-func slicesEqual(a, b []*string) bool {
-	for i, v := range a {
-		if v != b[i] {
-			return false
+		err = deleteRole(ctx, iam.New(sess), roleName(src.App, job.Workspace))
+		if err != nil {
+			return err
 		}
+
+		st.Step(terminal.StatusOK, "Deleted IAM execution role")
 	}
 
-	return true
+	return nil
 }
@@ -0,0 +1,171 @@
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Environment values with these prefixes reference SSM parameters and
+	// Secrets Manager secrets rather than holding the value itself.
+	ssmPrefix            = "ssm:"
+	secretsManagerPrefix = "secretsmanager:"
+
+	// SecretsResolve looks secret references up at deploy time and stores
+	// their values in the function configuration.
+	SecretsResolve = "resolve"
+
+	// SecretsReference stores the parameter name or secret ARN in the
+	// function configuration for the function to look up at runtime.
+	SecretsReference = "reference"
+)
+
+// environment builds the full set of environment variables for the function
+// from the Waypoint entrypoint config, the workspace and the configured
+// environment map, in increasing order of precedence.
+func (p *Platform) environment(
+	ctx context.Context,
+	sess *session.Session,
+	job *component.JobInfo,
+	deployConfig *component.DeploymentConfig,
+) (map[string]*string, error) {
+	env := job.Workspace
+	if env == "default" {
+		env = DefaultEnv
+	}
+
+	vars := map[string]*string{}
+
+	if deployConfig != nil {
+		for k, v := range deployConfig.Env() {
+			vars[k] = aws.String(v)
+		}
+	}
+
+	vars["ENV"] = aws.String(env)
+
+	var ssmSvc *ssm.SSM
+	var smSvc *secretsmanager.SecretsManager
+
+	for k, v := range p.config.Environment {
+		switch {
+		case strings.HasPrefix(v, ssmPrefix):
+			name := strings.TrimPrefix(v, ssmPrefix)
+
+			if p.config.Secrets == SecretsReference {
+				vars[k] = aws.String(name)
+				continue
+			}
+
+			if ssmSvc == nil {
+				ssmSvc = ssm.New(sess)
+			}
+
+			out, err := ssmSvc.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+				Name:           aws.String(name),
+				WithDecryption: aws.Bool(true),
+			})
+
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to read SSM parameter %s for %s", name, k)
+			}
+
+			vars[k] = out.Parameter.Value
+
+		case strings.HasPrefix(v, secretsManagerPrefix):
+			ref := strings.TrimPrefix(v, secretsManagerPrefix)
+
+			if p.config.Secrets == SecretsReference {
+				vars[k] = aws.String(ref)
+				continue
+			}
+
+			if smSvc == nil {
+				smSvc = secretsmanager.New(sess)
+			}
+
+			value, err := resolveSecret(ctx, smSvc, ref)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to resolve secret for %s", k)
+			}
+
+			vars[k] = aws.String(value)
+
+		default:
+			vars[k] = aws.String(v)
+		}
+	}
+
+	return vars, nil
+}
+
+// resolveSecret reads a secret given as "<arn>" or "<arn>#<key>", where key
+// selects a field of a JSON secret.
+func resolveSecret(
+	ctx context.Context,
+	smSvc *secretsmanager.SecretsManager,
+	ref string,
+) (string, error) {
+	id, key := ref, ""
+	if i := strings.LastIndex(ref, "#"); i >= 0 {
+		id, key = ref[:i], ref[i+1:]
+	}
+
+	out, err := smSvc.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(id),
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	secret := aws.StringValue(out.SecretString)
+	if key == "" {
+		return secret, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object, cannot select key %q", id, key)
+	}
+
+	v, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %q", id, key)
+	}
+
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func envEqual(a, b map[string]*string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		o, ok := b[k]
+		if !ok || aws.StringValue(o) != aws.StringValue(v) {
+			return false
+		}
+	}
+
+	return true
+}
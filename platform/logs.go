@@ -0,0 +1,229 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint/builtin/aws/utils"
+)
+
+const (
+	// How far back the first batch of logs reaches.
+	logLookback = 15 * time.Minute
+
+	// How often CloudWatch Logs is polled for new events.
+	logPollInterval = 2 * time.Second
+
+	// The most events returned by one poll; the rest follow on the next.
+	logBatchLimit = 1000
+
+	// How long CloudWatch Logs may take to update a stream's last event
+	// time, so that streams that look idle are still read.
+	logStreamLag = time.Hour
+
+	// The most streams FilterLogEvents reads at once.
+	maxLogStreams = 100
+)
+
+// Implement LogPlatform
+func (p *Platform) LogsFunc() interface{} {
+	return p.logs
+}
+
+func (p *Platform) logs(
+	ctx context.Context,
+	log hclog.Logger,
+	lv *component.LogViewer,
+	src *component.Source,
+	deployment *Deployment,
+) error {
+	region := deployment.Region
+	if region == "" {
+		region = p.config.Region
+	}
+
+	sess, err := utils.GetSession(&utils.SessionConfig{
+		Region: region,
+		Logger: log,
+	})
+	if err != nil {
+		return err
+	}
+
+	start := lv.StartingAt
+	if start.IsZero() {
+		start = time.Now().Add(-logLookback)
+	}
+
+	t := &logTail{
+		cwlSvc:  cloudwatchlogs.New(sess),
+		group:   fmt.Sprintf("/aws/lambda/%s", src.App),
+		version: deployment.Version,
+		start:   start.UnixNano() / int64(time.Millisecond),
+		seen:    map[string]bool{},
+	}
+
+	// A limit of zero follows the logs until the viewer is closed
+	sent := 0
+	for {
+		events, err := t.next(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, ev := range events {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case lv.Output <- ev:
+			}
+
+			sent++
+			if lv.Limit > 0 && sent >= lv.Limit {
+				return nil
+			}
+		}
+	}
+}
+
+// logTail reads the function's log group, keeping only the streams written
+// by a single published version.
+type logTail struct {
+	cwlSvc  *cloudwatchlogs.CloudWatchLogs
+	group   string
+	version string
+
+	// start is the timestamp (in ms) to read from, and seen the IDs of the
+	// events already returned at that timestamp.
+	start int64
+	seen  map[string]bool
+}
+
+// next blocks until there are new log events for the version.
+func (t *logTail) next(ctx context.Context) ([]component.LogEvent, error) {
+	for {
+		events, err := t.read(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(events) > 0 {
+			return events, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(logPollInterval):
+		}
+	}
+}
+
+// streams returns the version's streams with events since the tail's start,
+// most recently written first.
+func (t *logTail) streams(ctx context.Context) ([]*string, error) {
+	// Lambda names streams "<date>/[<version>]<id>"
+	marker := fmt.Sprintf("[%s]", t.version)
+	cutoff := t.start - int64(logStreamLag/time.Millisecond)
+
+	var names []*string
+	err := t.cwlSvc.DescribeLogStreamsPagesWithContext(ctx, &cloudwatchlogs.DescribeLogStreamsInput{
+		LogGroupName: aws.String(t.group),
+		OrderBy:      aws.String(cloudwatchlogs.OrderByLastEventTime),
+		Descending:   aws.Bool(true),
+	}, func(out *cloudwatchlogs.DescribeLogStreamsOutput, lastPage bool) bool {
+		for _, s := range out.LogStreams {
+			if s.LastEventTimestamp != nil && *s.LastEventTimestamp < cutoff {
+				return false
+			}
+
+			if strings.Contains(aws.StringValue(s.LogStreamName), marker) {
+				names = append(names, s.LogStreamName)
+			}
+
+			if len(names) == maxLogStreams {
+				return false
+			}
+		}
+
+		return true
+	})
+
+	return names, err
+}
+
+func (t *logTail) read(ctx context.Context) ([]component.LogEvent, error) {
+	streams, err := t.streams(ctx)
+	if err != nil {
+		// The log group only exists once the function has been invoked
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudwatchlogs.ErrCodeResourceNotFoundException {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Without stream names the whole group would be read
+	if len(streams) == 0 {
+		return nil, nil
+	}
+
+	var events []component.LogEvent
+	latest, latestSeen := t.start, map[string]bool{}
+
+	err = t.cwlSvc.FilterLogEventsPagesWithContext(ctx, &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName:   aws.String(t.group),
+		LogStreamNames: streams,
+		StartTime:      aws.Int64(t.start),
+		Limit:          aws.Int64(logBatchLimit),
+	}, func(out *cloudwatchlogs.FilterLogEventsOutput, lastPage bool) bool {
+		for _, ev := range out.Events {
+			id := aws.StringValue(ev.EventId)
+			ts := aws.Int64Value(ev.Timestamp)
+
+			if ts == t.start && t.seen[id] {
+				continue
+			}
+
+			if ts > latest {
+				latest, latestSeen = ts, map[string]bool{}
+			}
+			if ts == latest {
+				latestSeen[id] = true
+			}
+
+			events = append(events, component.LogEvent{
+				Partition: aws.StringValue(ev.LogStreamName),
+				Timestamp: time.Unix(0, ts*int64(time.Millisecond)),
+				Message:   strings.TrimRight(aws.StringValue(ev.Message), "\n"),
+			})
+		}
+
+		// Events are returned oldest first, so the rest are read from latest
+		// on the next poll
+		return len(events) < logBatchLimit
+	})
+
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudwatchlogs.ErrCodeResourceNotFoundException {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if latest == t.start {
+		for id := range latestSeen {
+			t.seen[id] = true
+		}
+	} else {
+		t.start, t.seen = latest, latestSeen
+	}
+
+	return events, nil
+}
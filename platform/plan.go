@@ -0,0 +1,106 @@
+package platform
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/hashicorp/waypoint/builtin/aws/ecr"
+	"github.com/pkg/errors"
+)
+
+// ErrPlanOnly is returned once a plan has been shown, so that Waypoint
+// doesn't record a deployment that was never made.
+var ErrPlanOnly = errors.New("plan_only is set, no changes were made")
+
+// plan reads the function and shows how a deploy would change it, without
+// making any changes.
+func (p *Platform) plan(
+	ctx context.Context,
+	sg terminal.StepGroup,
+	ui terminal.UI,
+	sess *session.Session,
+	src *component.Source,
+	job *component.JobInfo,
+	img *ecr.Image,
+	vars map[string]*string,
+) error {
+	step := sg.Add("Reading Lambda function: %s", src.App)
+	defer step.Abort()
+
+	roleArn := p.config.RoleArn
+	if roleArn == "" {
+		name := roleName(src.App, job.Workspace)
+
+		out, err := iam.New(sess).GetRoleWithContext(ctx, &iam.GetRoleInput{
+			RoleName: aws.String(name),
+		})
+
+		switch {
+		case err == nil:
+			roleArn = aws.StringValue(out.Role.Arn)
+		case isCode(err, iam.ErrCodeNoSuchEntityException):
+			roleArn = "new role " + name
+		default:
+			return errors.Wrapf(err, "unable to read IAM role %s", name)
+		}
+	}
+
+	cur := &lambda.FunctionConfiguration{}
+	var image string
+
+	out, err := lambda.New(sess).GetFunctionWithContext(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(src.App),
+	})
+
+	switch {
+	case err == nil:
+		cur = out.Configuration
+		if out.Code != nil {
+			image = aws.StringValue(out.Code.ImageUri)
+		}
+	case isCode(err, lambda.ErrCodeResourceNotFoundException):
+		step.Update("Lambda function %s doesn't exist yet", src.App)
+	default:
+		return errors.Wrapf(err, "unable to read function %s", src.App)
+	}
+
+	_, fields := p.spec(roleArn, vars).diff(cur)
+	fields = append(fields, fieldChange{
+		field:   "image",
+		current: formatValue(image),
+		desired: img.Name(),
+		changed: image != img.Name(),
+	})
+
+	step.Done()
+	sg.Wait()
+
+	ui.Table(planTable(fields))
+
+	return ErrPlanOnly
+}
+
+// planTable renders fields as a table of current and desired values, with
+// the settings that would change highlighted.
+func planTable(fields []fieldChange) *terminal.Table {
+	tbl := terminal.NewTable("Setting", "Current", "Desired", "Action")
+
+	for _, f := range fields {
+		action, color := "none", ""
+		if f.changed {
+			action, color = "update", terminal.Yellow
+		}
+
+		tbl.Rich(
+			[]string{f.field, f.current, f.desired, action},
+			[]string{color, color, color, color},
+		)
+	}
+
+	return tbl
+}
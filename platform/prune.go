@@ -0,0 +1,170 @@
+package platform
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/pkg/errors"
+)
+
+// pruneVersions deletes the oldest published versions beyond keep_versions,
+// skipping any version still referenced by an alias or an EventBridge target.
+// It returns the versions it deleted.
+func (p *Platform) pruneVersions(
+	ctx context.Context,
+	lamSvc *lambda.Lambda,
+	evSvc *eventbridge.EventBridge,
+	funcArn string,
+) ([]string, error) {
+	type version struct {
+		n   int
+		arn string
+	}
+
+	var versions []version
+	err := lamSvc.ListVersionsByFunctionPagesWithContext(ctx, &lambda.ListVersionsByFunctionInput{
+		FunctionName: aws.String(funcArn),
+	}, func(out *lambda.ListVersionsByFunctionOutput, lastPage bool) bool {
+		for _, v := range out.Versions {
+			// Skip $LATEST
+			n, err := strconv.Atoi(aws.StringValue(v.Version))
+			if err != nil {
+				continue
+			}
+
+			versions = append(versions, version{n: n, arn: aws.StringValue(v.FunctionArn)})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list function versions")
+	}
+
+	if len(versions) <= p.config.KeepVersions {
+		return nil, nil
+	}
+
+	// Newest first, so everything past keep_versions is a candidate
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].n > versions[j].n
+	})
+	candidates := versions[p.config.KeepVersions:]
+
+	protected, err := referencedVersions(ctx, lamSvc, funcArn)
+	if err != nil {
+		return nil, err
+	}
+
+	buses, err := eventBuses(ctx, evSvc)
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []string
+	for _, v := range candidates {
+		num := strconv.Itoa(v.n)
+		if protected[num] {
+			continue
+		}
+
+		targeted, err := targetedByRule(ctx, evSvc, buses, v.arn)
+		if err != nil {
+			return pruned, err
+		}
+		if targeted {
+			continue
+		}
+
+		_, err = lamSvc.DeleteFunctionWithContext(ctx, &lambda.DeleteFunctionInput{
+			FunctionName: aws.String(funcArn),
+			Qualifier:    aws.String(num),
+		})
+		if err != nil {
+			return pruned, errors.Wrapf(err, "unable to delete version %s", num)
+		}
+
+		pruned = append(pruned, num)
+	}
+
+	return pruned, nil
+}
+
+// referencedVersions returns the versions that aliases route to.
+func referencedVersions(
+	ctx context.Context,
+	lamSvc *lambda.Lambda,
+	funcArn string,
+) (map[string]bool, error) {
+	refs := map[string]bool{}
+
+	err := lamSvc.ListAliasesPagesWithContext(ctx, &lambda.ListAliasesInput{
+		FunctionName: aws.String(funcArn),
+	}, func(out *lambda.ListAliasesOutput, lastPage bool) bool {
+		for _, a := range out.Aliases {
+			refs[aws.StringValue(a.FunctionVersion)] = true
+
+			if a.RoutingConfig != nil {
+				for v := range a.RoutingConfig.AdditionalVersionWeights {
+					refs[v] = true
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list aliases")
+	}
+
+	return refs, nil
+}
+
+// eventBuses returns the names of all event buses in the region.
+func eventBuses(ctx context.Context, evSvc *eventbridge.EventBridge) ([]string, error) {
+	var names []string
+
+	input := &eventbridge.ListEventBusesInput{}
+	for {
+		out, err := evSvc.ListEventBusesWithContext(ctx, input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to list event buses")
+		}
+
+		for _, b := range out.EventBuses {
+			names = append(names, aws.StringValue(b.Name))
+		}
+
+		if out.NextToken == nil {
+			return names, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// targetedByRule reports whether any EventBridge rule on the given buses
+// targets arn.
+func targetedByRule(
+	ctx context.Context,
+	evSvc *eventbridge.EventBridge,
+	buses []string,
+	arn string,
+) (bool, error) {
+	for _, bus := range buses {
+		out, err := evSvc.ListRuleNamesByTargetWithContext(ctx, &eventbridge.ListRuleNamesByTargetInput{
+			TargetArn:    aws.String(arn),
+			EventBusName: aws.String(bus),
+		})
+		if err != nil {
+			return false, errors.Wrapf(err, "unable to list rules targeting %s", arn)
+		}
+
+		if len(out.RuleNames) > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
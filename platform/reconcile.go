@@ -0,0 +1,256 @@
+package platform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// functionSpec is the desired configuration of the function, built from
+// DeployConfig. Anything not set here is removed from the function.
+type functionSpec struct {
+	role             string
+	memory           int64
+	timeout          int64
+	environment      map[string]*string
+	subnetIds        []string
+	securityGroupIds []string
+	efsArn           string
+	efsMountPath     string
+	tracingMode      string
+}
+
+// fieldChange describes one setting of the function and whether it differs
+// from its spec.
+type fieldChange struct {
+	field   string
+	current string
+	desired string
+	changed bool
+}
+
+func (p *Platform) spec(roleArn string, vars map[string]*string) *functionSpec {
+	s := &functionSpec{
+		role:             roleArn,
+		memory:           p.config.Memory,
+		timeout:          p.config.Timeout,
+		environment:      vars,
+		subnetIds:        aws.StringValueSlice(p.config.SubnetIds),
+		securityGroupIds: aws.StringValueSlice(p.config.SecurityGroupIds),
+		efsArn:           aws.StringValue(p.config.EfsAccessPointArn),
+		efsMountPath:     aws.StringValue(p.config.EfsMountPath),
+		tracingMode:      p.config.TracingMode,
+	}
+
+	if s.memory == 0 {
+		s.memory = DefaultMemory
+	}
+
+	if s.timeout == 0 {
+		s.timeout = DefaultTimeout
+	}
+
+	// PassThrough is what Lambda uses when tracing isn't configured
+	if s.tracingMode == "" {
+		s.tracingMode = lambda.TracingModePassThrough
+	}
+
+	return s
+}
+
+func (s *functionSpec) vpcConfig() *lambda.VpcConfig {
+	return &lambda.VpcConfig{
+		SubnetIds:        aws.StringSlice(s.subnetIds),
+		SecurityGroupIds: aws.StringSlice(s.securityGroupIds),
+	}
+}
+
+func (s *functionSpec) fileSystemConfigs() []*lambda.FileSystemConfig {
+	if s.efsArn == "" {
+		return []*lambda.FileSystemConfig{}
+	}
+
+	return []*lambda.FileSystemConfig{
+		{
+			Arn:            aws.String(s.efsArn),
+			LocalMountPath: aws.String(s.efsMountPath),
+		},
+	}
+}
+
+// diff compares the current function configuration with the spec, returning
+// the minimal update that reconciles them, or nil if they already match,
+// along with a description of every setting compared.
+func (s *functionSpec) diff(cur *lambda.FunctionConfiguration) (*lambda.UpdateFunctionConfigurationInput, []fieldChange) {
+	update := &lambda.UpdateFunctionConfigurationInput{
+		FunctionName: cur.FunctionArn,
+	}
+
+	var fields []fieldChange
+	changed := false
+	field := func(name, current, desired string, differs bool) {
+		fields = append(fields, fieldChange{field: name, current: current, desired: desired, changed: differs})
+		changed = changed || differs
+	}
+
+	roleChanged := aws.StringValue(cur.Role) != s.role
+	if roleChanged {
+		update.Role = aws.String(s.role)
+	}
+	field("role", formatValue(aws.StringValue(cur.Role)), s.role, roleChanged)
+
+	memoryChanged := aws.Int64Value(cur.MemorySize) != s.memory
+	if memoryChanged {
+		update.MemorySize = aws.Int64(s.memory)
+	}
+	field("memory", formatInt(aws.Int64Value(cur.MemorySize)), formatInt(s.memory), memoryChanged)
+
+	timeoutChanged := aws.Int64Value(cur.Timeout) != s.timeout
+	if timeoutChanged {
+		update.Timeout = aws.Int64(s.timeout)
+	}
+	field("timeout", formatInt(aws.Int64Value(cur.Timeout)), formatInt(s.timeout), timeoutChanged)
+
+	var curVars map[string]*string
+	if cur.Environment != nil {
+		curVars = cur.Environment.Variables
+	}
+
+	envChanged := !envEqual(curVars, s.environment)
+	if envChanged {
+		// An empty map clears variables removed from the config
+		vars := s.environment
+		if vars == nil {
+			vars = map[string]*string{}
+		}
+
+		update.Environment = &lambda.Environment{Variables: vars}
+	}
+	field("environment", formatEnv(curVars, nil), formatEnv(s.environment, curVars), envChanged)
+
+	var curSubnets, curGroups []*string
+	if cur.VpcConfig != nil {
+		curSubnets = cur.VpcConfig.SubnetIds
+		curGroups = cur.VpcConfig.SecurityGroupIds
+	}
+
+	subnetsChanged := !setEqual(aws.StringValueSlice(curSubnets), s.subnetIds)
+	groupsChanged := !setEqual(aws.StringValueSlice(curGroups), s.securityGroupIds)
+
+	// Both lists are always sent together; empty lists detach the VPC
+	if subnetsChanged || groupsChanged {
+		update.VpcConfig = s.vpcConfig()
+	}
+	field("subnet_ids", formatList(curSubnets), formatList(aws.StringSlice(s.subnetIds)), subnetsChanged)
+	field("security_group_ids", formatList(curGroups), formatList(aws.StringSlice(s.securityGroupIds)), groupsChanged)
+
+	var curEfsArn, curEfsPath string
+	if len(cur.FileSystemConfigs) > 0 {
+		curEfsArn = aws.StringValue(cur.FileSystemConfigs[0].Arn)
+		curEfsPath = aws.StringValue(cur.FileSystemConfigs[0].LocalMountPath)
+	}
+
+	efsChanged := curEfsArn != s.efsArn || curEfsPath != s.efsMountPath || len(cur.FileSystemConfigs) > 1
+	if efsChanged {
+		// An empty list detaches the file system
+		update.FileSystemConfigs = s.fileSystemConfigs()
+	}
+	field("efs", formatEfs(curEfsArn, curEfsPath), formatEfs(s.efsArn, s.efsMountPath), efsChanged)
+
+	curTracing := lambda.TracingModePassThrough
+	if cur.TracingConfig != nil && cur.TracingConfig.Mode != nil {
+		curTracing = *cur.TracingConfig.Mode
+	}
+
+	tracingChanged := curTracing != s.tracingMode
+	if tracingChanged {
+		update.TracingConfig = &lambda.TracingConfig{Mode: aws.String(s.tracingMode)}
+	}
+	field("tracing_mode", curTracing, s.tracingMode, tracingChanged)
+
+	if !changed {
+		return nil, fields
+	}
+
+	return update, fields
+}
+
+// setEqual reports whether a and b hold the same strings, ignoring order.
+func setEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	seen := map[string]int{}
+	for _, v := range a {
+		seen[v]++
+	}
+
+	for _, v := range b {
+		if seen[v] == 0 {
+			return false
+		}
+		seen[v]--
+	}
+
+	return true
+}
+
+func formatValue(v string) string {
+	if v == "" {
+		return "-"
+	}
+
+	return v
+}
+
+func formatInt(v int64) string {
+	if v == 0 {
+		return "-"
+	}
+
+	return fmt.Sprint(v)
+}
+
+func formatList(v []*string) string {
+	if len(v) == 0 {
+		return "-"
+	}
+
+	return strings.Join(aws.StringValueSlice(v), ", ")
+}
+
+// formatEnv lists the names of vars, never their values, which may hold
+// secrets. When compared with current, names whose value differs are marked.
+func formatEnv(vars, current map[string]*string) string {
+	if len(vars) == 0 {
+		return "-"
+	}
+
+	var names []string
+	for k, v := range vars {
+		if current != nil {
+			cur, ok := current[k]
+			if !ok {
+				k += " (added)"
+			} else if aws.StringValue(cur) != aws.StringValue(v) {
+				k += " (changed)"
+			}
+		}
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+func formatEfs(arn, path string) string {
+	if arn == "" {
+		return "-"
+	}
+
+	return arn + " at " + path
+}
@@ -0,0 +1,172 @@
+package platform
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+func TestDiff(t *testing.T) {
+	const role = "arn:aws:iam::123456789012:role/app"
+	const efs = "arn:aws:elasticfilesystem:eu-west-1:123456789012:access-point/fsap-1"
+
+	// current is a function matching the spec built by desired
+	current := func() *lambda.FunctionConfiguration {
+		return &lambda.FunctionConfiguration{
+			FunctionArn: aws.String("arn:aws:lambda:eu-west-1:123456789012:function:app"),
+			Role:        aws.String(role),
+			MemorySize:  aws.Int64(256),
+			Timeout:     aws.Int64(60),
+			Environment: &lambda.EnvironmentResponse{
+				Variables: map[string]*string{"PORT": aws.String("8080")},
+			},
+			VpcConfig: &lambda.VpcConfigResponse{
+				SubnetIds:        aws.StringSlice([]string{"subnet-1", "subnet-2"}),
+				SecurityGroupIds: aws.StringSlice([]string{"sg-1"}),
+			},
+			FileSystemConfigs: []*lambda.FileSystemConfig{
+				{Arn: aws.String(efs), LocalMountPath: aws.String("/mnt/data")},
+			},
+			TracingConfig: &lambda.TracingConfigResponse{Mode: aws.String(lambda.TracingModePassThrough)},
+		}
+	}
+
+	desired := func() *functionSpec {
+		return &functionSpec{
+			role:             role,
+			memory:           256,
+			timeout:          60,
+			environment:      map[string]*string{"PORT": aws.String("8080")},
+			subnetIds:        []string{"subnet-2", "subnet-1"},
+			securityGroupIds: []string{"sg-1"},
+			efsArn:           efs,
+			efsMountPath:     "/mnt/data",
+			tracingMode:      lambda.TracingModePassThrough,
+		}
+	}
+
+	cases := []struct {
+		name    string
+		spec    func(s *functionSpec)
+		cur     func(c *lambda.FunctionConfiguration)
+		changed []string
+		check   func(u *lambda.UpdateFunctionConfigurationInput) bool
+	}{
+		{
+			name: "unchanged",
+		},
+		{
+			name:    "memory",
+			spec:    func(s *functionSpec) { s.memory = 512 },
+			changed: []string{"memory"},
+			check: func(u *lambda.UpdateFunctionConfigurationInput) bool {
+				return aws.Int64Value(u.MemorySize) == 512 && u.Timeout == nil && u.VpcConfig == nil
+			},
+		},
+		{
+			name:    "environment value",
+			spec:    func(s *functionSpec) { s.environment["PORT"] = aws.String("9090") },
+			changed: []string{"environment"},
+		},
+		{
+			name:    "environment removed",
+			spec:    func(s *functionSpec) { s.environment = nil },
+			changed: []string{"environment"},
+			check: func(u *lambda.UpdateFunctionConfigurationInput) bool {
+				return u.Environment != nil && u.Environment.Variables != nil && len(u.Environment.Variables) == 0
+			},
+		},
+		{
+			name:    "vpc removed",
+			spec:    func(s *functionSpec) { s.subnetIds, s.securityGroupIds = nil, nil },
+			changed: []string{"subnet_ids", "security_group_ids"},
+			check: func(u *lambda.UpdateFunctionConfigurationInput) bool {
+				return u.VpcConfig != nil && u.VpcConfig.SubnetIds != nil && len(u.VpcConfig.SubnetIds) == 0 &&
+					u.VpcConfig.SecurityGroupIds != nil && len(u.VpcConfig.SecurityGroupIds) == 0
+			},
+		},
+		{
+			name:    "security group changed",
+			spec:    func(s *functionSpec) { s.securityGroupIds = []string{"sg-2"} },
+			changed: []string{"security_group_ids"},
+			check: func(u *lambda.UpdateFunctionConfigurationInput) bool {
+				// Subnets are sent along with the groups
+				return len(u.VpcConfig.SubnetIds) == 2 && aws.StringValue(u.VpcConfig.SecurityGroupIds[0]) == "sg-2"
+			},
+		},
+		{
+			name:    "efs removed",
+			spec:    func(s *functionSpec) { s.efsArn, s.efsMountPath = "", "" },
+			changed: []string{"efs"},
+			check: func(u *lambda.UpdateFunctionConfigurationInput) bool {
+				return u.FileSystemConfigs != nil && len(u.FileSystemConfigs) == 0
+			},
+		},
+		{
+			name:    "efs mount path",
+			spec:    func(s *functionSpec) { s.efsMountPath = "/mnt/other" },
+			changed: []string{"efs"},
+		},
+		{
+			name: "nil current settings",
+			spec: func(s *functionSpec) {
+				s.environment, s.subnetIds, s.securityGroupIds, s.efsArn, s.efsMountPath = nil, nil, nil, "", ""
+			},
+			cur: func(c *lambda.FunctionConfiguration) {
+				c.Environment, c.VpcConfig, c.FileSystemConfigs, c.TracingConfig = nil, nil, nil, nil
+			},
+		},
+		{
+			name: "nil current settings with desired ones",
+			cur: func(c *lambda.FunctionConfiguration) {
+				c.Environment, c.VpcConfig, c.FileSystemConfigs, c.TracingConfig = nil, nil, nil, nil
+			},
+			changed: []string{"environment", "subnet_ids", "security_group_ids", "efs"},
+		},
+		{
+			name:    "new function",
+			cur:     func(c *lambda.FunctionConfiguration) { *c = lambda.FunctionConfiguration{} },
+			changed: []string{"role", "memory", "timeout", "environment", "subnet_ids", "security_group_ids", "efs"},
+		},
+		{
+			name:    "tracing",
+			spec:    func(s *functionSpec) { s.tracingMode = lambda.TracingModeActive },
+			changed: []string{"tracing_mode"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, cur := desired(), current()
+			if c.spec != nil {
+				c.spec(s)
+			}
+			if c.cur != nil {
+				c.cur(cur)
+			}
+
+			update, fields := s.diff(cur)
+
+			var changed []string
+			for _, f := range fields {
+				if f.changed {
+					changed = append(changed, f.field)
+				}
+			}
+
+			if !reflect.DeepEqual(changed, c.changed) {
+				t.Errorf("changed %v, want %v", changed, c.changed)
+			}
+
+			if (update != nil) != (len(c.changed) > 0) {
+				t.Fatalf("update %v, want one only if something changed", update)
+			}
+
+			if c.check != nil && !c.check(update) {
+				t.Errorf("unexpected update %v", update)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/pkg/errors"
+)
+
+const (
+	basicExecutionPolicy = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+	vpcAccessPolicy      = "arn:aws:iam::aws:policy/service-role/AWSLambdaVPCAccessExecutionRole"
+	efsClientPolicy      = "arn:aws:iam::aws:policy/AmazonElasticFileSystemClientReadWriteAccess"
+	xrayWritePolicy      = "arn:aws:iam::aws:policy/AWSXRayDaemonWriteAccess"
+
+	// The name of the inline policy holding the configured statements.
+	inlinePolicyName = "waypoint-statements"
+
+	lambdaAssumeRolePolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": "lambda.amazonaws.com"},
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}`
+)
+
+// The managed policies the plugin attaches to roles it owns. Anything else
+// attached to the role is left alone.
+var managedPolicies = []string{
+	basicExecutionPolicy,
+	vpcAccessPolicy,
+	efsClientPolicy,
+	xrayWritePolicy,
+}
+
+type PolicyStatement struct {
+	Effect    string   `hcl:"effect,optional"`
+	Actions   []string `hcl:"actions"`
+	Resources []string `hcl:"resources"`
+}
+
+// roleName returns the name of the execution role the plugin manages for the
+// app in the given workspace.
+func roleName(app, workspace string) string {
+	name := fmt.Sprintf("waypoint-%s-%s", app, workspace)
+
+	// IAM role names are limited to 64 characters
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return name
+}
+
+// policies returns the managed policies the function's configuration needs.
+func (p *Platform) policies() map[string]bool {
+	want := map[string]bool{
+		basicExecutionPolicy: true,
+	}
+
+	if len(p.config.SubnetIds) > 0 {
+		want[vpcAccessPolicy] = true
+	}
+
+	if p.config.EfsAccessPointArn != nil {
+		want[efsClientPolicy] = true
+	}
+
+	if p.config.TracingMode == lambda.TracingModeActive {
+		want[xrayWritePolicy] = true
+	}
+
+	return want
+}
+
+// ensureRole creates the plugin-managed execution role if needed and brings
+// its policies in line with the current configuration, returning its ARN.
+func (p *Platform) ensureRole(
+	ctx context.Context,
+	iamSvc *iam.IAM,
+	src *component.Source,
+	job *component.JobInfo,
+) (string, error) {
+	name := roleName(src.App, job.Workspace)
+
+	var role *iam.Role
+
+	out, err := iamSvc.GetRoleWithContext(ctx, &iam.GetRoleInput{
+		RoleName: aws.String(name),
+	})
+
+	if err == nil {
+		role = out.Role
+	} else {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != iam.ErrCodeNoSuchEntityException {
+			return "", errors.Wrapf(err, "unable to read IAM role %s", name)
+		}
+
+		created, err := iamSvc.CreateRoleWithContext(ctx, &iam.CreateRoleInput{
+			RoleName:                 aws.String(name),
+			AssumeRolePolicyDocument: aws.String(lambdaAssumeRolePolicy),
+			Description:              aws.String(fmt.Sprintf("waypoint %s execution role", src.App)),
+			Tags: []*iam.Tag{
+				{Key: aws.String("waypoint.app"), Value: aws.String(src.App)},
+				{Key: aws.String("waypoint.workspace"), Value: aws.String(job.Workspace)},
+			},
+		})
+
+		if err != nil {
+			return "", errors.Wrapf(err, "unable to create IAM role %s", name)
+		}
+
+		role = created.Role
+	}
+
+	attached, err := iamSvc.ListAttachedRolePoliciesWithContext(ctx, &iam.ListAttachedRolePoliciesInput{
+		RoleName: aws.String(name),
+	})
+
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to list policies of IAM role %s", name)
+	}
+
+	have := map[string]bool{}
+	for _, a := range attached.AttachedPolicies {
+		have[aws.StringValue(a.PolicyArn)] = true
+	}
+
+	want := p.policies()
+
+	for _, arn := range managedPolicies {
+		switch {
+		case want[arn] && !have[arn]:
+			_, err = iamSvc.AttachRolePolicyWithContext(ctx, &iam.AttachRolePolicyInput{
+				RoleName:  aws.String(name),
+				PolicyArn: aws.String(arn),
+			})
+		case !want[arn] && have[arn]:
+			_, err = iamSvc.DetachRolePolicyWithContext(ctx, &iam.DetachRolePolicyInput{
+				RoleName:  aws.String(name),
+				PolicyArn: aws.String(arn),
+			})
+		}
+
+		if err != nil {
+			return "", errors.Wrapf(err, "unable to update policy %s on IAM role %s", arn, name)
+		}
+	}
+
+	if err := p.putInlinePolicy(ctx, iamSvc, name); err != nil {
+		return "", err
+	}
+
+	return *role.Arn, nil
+}
+
+// putInlinePolicy writes the configured policy statements to the role, or
+// removes the inline policy if there are none.
+func (p *Platform) putInlinePolicy(
+	ctx context.Context,
+	iamSvc *iam.IAM,
+	name string,
+) error {
+	if len(p.config.PolicyStatements) == 0 {
+		_, err := iamSvc.DeleteRolePolicyWithContext(ctx, &iam.DeleteRolePolicyInput{
+			RoleName:   aws.String(name),
+			PolicyName: aws.String(inlinePolicyName),
+		})
+
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			return nil
+		}
+
+		return err
+	}
+
+	type statement struct {
+		Effect   string
+		Action   []string
+		Resource []string
+	}
+
+	doc := struct {
+		Version   string
+		Statement []statement
+	}{
+		Version: "2012-10-17",
+	}
+
+	for _, s := range p.config.PolicyStatements {
+		effect := s.Effect
+		if effect == "" {
+			effect = "Allow"
+		}
+
+		doc.Statement = append(doc.Statement, statement{
+			Effect:   effect,
+			Action:   s.Actions,
+			Resource: s.Resources,
+		})
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = iamSvc.PutRolePolicyWithContext(ctx, &iam.PutRolePolicyInput{
+		RoleName:       aws.String(name),
+		PolicyName:     aws.String(inlinePolicyName),
+		PolicyDocument: aws.String(string(data)),
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "unable to put inline policy on IAM role %s", name)
+	}
+
+	return nil
+}
+
+// deleteRole removes the plugin-managed execution role along with its
+// policies. A role that doesn't exist is not an error.
+func deleteRole(ctx context.Context, iamSvc *iam.IAM, name string) error {
+	attached, err := iamSvc.ListAttachedRolePoliciesWithContext(ctx, &iam.ListAttachedRolePoliciesInput{
+		RoleName: aws.String(name),
+	})
+
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			return nil
+		}
+		return err
+	}
+
+	for _, a := range attached.AttachedPolicies {
+		_, err = iamSvc.DetachRolePolicyWithContext(ctx, &iam.DetachRolePolicyInput{
+			RoleName:  aws.String(name),
+			PolicyArn: a.PolicyArn,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	inline, err := iamSvc.ListRolePoliciesWithContext(ctx, &iam.ListRolePoliciesInput{
+		RoleName: aws.String(name),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, policy := range inline.PolicyNames {
+		_, err = iamSvc.DeleteRolePolicyWithContext(ctx, &iam.DeleteRolePolicyInput{
+			RoleName:   aws.String(name),
+			PolicyName: policy,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = iamSvc.DeleteRoleWithContext(ctx, &iam.DeleteRoleInput{
+		RoleName: aws.String(name),
+	})
+
+	return err
+}
@@ -0,0 +1,227 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/pkg/errors"
+)
+
+type SmokeTestConfig struct {
+	// Payload is inline JSON sent to the function; PayloadFile is a path,
+	// relative to the app, to a file holding it.
+	Payload     string `hcl:"payload,optional"`
+	PayloadFile string `hcl:"payload_file,optional"`
+
+	// MaxDuration fails the test if the invocation takes longer.
+	MaxDuration string `hcl:"max_duration,optional"`
+
+	// Assertions on the JSON response of the function.
+	Assertions []*SmokeTestAssertion `hcl:"assert,block"`
+}
+
+type SmokeTestAssertion struct {
+	// Path is a JSON path into the response, such as "$.body.items[0].id".
+	Path string `hcl:"path"`
+
+	// Equals is compared with the value at Path. Strings are compared as is,
+	// anything else with its JSON encoding.
+	Equals string `hcl:"equals"`
+}
+
+// Lambda reports how long an invocation ran, before rounding up to the billed
+// duration, in its tail log.
+var reportDuration = regexp.MustCompile(`REPORT .*?\bDuration: ([0-9.]+) ms`)
+
+func (c *SmokeTestConfig) validate() error {
+	if c.Payload != "" && c.PayloadFile != "" {
+		return fmt.Errorf("smoke_test accepts only one of payload and payload_file")
+	}
+
+	if c.Payload != "" && !json.Valid([]byte(c.Payload)) {
+		return fmt.Errorf("smoke_test payload is not valid JSON")
+	}
+
+	if c.MaxDuration != "" {
+		if _, err := time.ParseDuration(c.MaxDuration); err != nil {
+			return fmt.Errorf("invalid smoke_test max_duration %q: %s", c.MaxDuration, err)
+		}
+	}
+
+	for _, a := range c.Assertions {
+		if !strings.HasPrefix(a.Path, "$") {
+			return fmt.Errorf("smoke_test assert path %q must start with $", a.Path)
+		}
+	}
+
+	return nil
+}
+
+// smokeTest synchronously invokes the published version and checks the
+// response against the configured expectations, writing the tail of the
+// function's log to the step.
+func (p *Platform) smokeTest(
+	ctx context.Context,
+	lamSvc *lambda.Lambda,
+	step terminal.Step,
+	src *component.Source,
+	verarn string,
+) error {
+	cfg := p.config.SmokeTest
+
+	payload := []byte(cfg.Payload)
+	if cfg.PayloadFile != "" {
+		path := cfg.PayloadFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(src.Path, path)
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "unable to read smoke test payload")
+		}
+
+		payload = data
+	}
+
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+
+	start := time.Now()
+
+	out, err := lamSvc.InvokeWithContext(ctx, &lambda.InvokeInput{
+		FunctionName:   aws.String(verarn),
+		InvocationType: aws.String(lambda.InvocationTypeRequestResponse),
+		LogType:        aws.String(lambda.LogTypeTail),
+		Payload:        payload,
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "unable to invoke function")
+	}
+
+	duration := time.Since(start)
+
+	if out.LogResult != nil {
+		tail, err := base64.StdEncoding.DecodeString(*out.LogResult)
+		if err == nil {
+			step.TermOutput().Write(tail)
+
+			if m := reportDuration.FindSubmatch(tail); m != nil {
+				ms, _ := strconv.ParseFloat(string(m[1]), 64)
+				duration = time.Duration(ms * float64(time.Millisecond))
+			}
+		}
+	}
+
+	if out.FunctionError != nil {
+		return fmt.Errorf("function returned a %s error: %s",
+			*out.FunctionError, bytes.TrimSpace(out.Payload))
+	}
+
+	if cfg.MaxDuration != "" {
+		max, _ := time.ParseDuration(cfg.MaxDuration)
+		if duration > max {
+			return fmt.Errorf("invocation took %s, more than the allowed %s", duration, max)
+		}
+	}
+
+	if len(cfg.Assertions) == 0 {
+		return nil
+	}
+
+	var resp interface{}
+	if err := json.Unmarshal(out.Payload, &resp); err != nil {
+		return fmt.Errorf("response is not valid JSON: %s", err)
+	}
+
+	for _, a := range cfg.Assertions {
+		v, err := jsonPath(resp, a.Path)
+		if err != nil {
+			return err
+		}
+
+		actual, ok := v.(string)
+		if !ok {
+			data, _ := json.Marshal(v)
+			actual = string(data)
+		}
+
+		if actual != a.Equals {
+			return fmt.Errorf("expected %s to equal %q, got %q", a.Path, a.Equals, actual)
+		}
+	}
+
+	return nil
+}
+
+// jsonPath resolves a simple JSON path made of "$", ".key" and "[index]"
+// segments against a decoded JSON document.
+func jsonPath(doc interface{}, path string) (interface{}, error) {
+	rest := strings.TrimPrefix(path, "$")
+	cur := doc
+
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+
+			key := rest[:end]
+			rest = rest[end:]
+
+			obj, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: %q is not an object", path, key)
+			}
+
+			cur, ok = obj[key]
+			if !ok {
+				return nil, fmt.Errorf("%s: no key %q in response", path, key)
+			}
+
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("%s: unterminated index", path)
+			}
+
+			idx, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid index %q", path, rest[1:end])
+			}
+
+			rest = rest[end+1:]
+
+			arr, ok := cur.([]interface{})
+			if !ok || idx < 0 || idx >= len(arr) {
+				return nil, fmt.Errorf("%s: index %d out of range", path, idx)
+			}
+
+			cur = arr[idx]
+
+		default:
+			return nil, fmt.Errorf("%s: unexpected %q", path, rest[0])
+		}
+	}
+
+	return cur, nil
+}
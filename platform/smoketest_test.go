@@ -0,0 +1,58 @@
+package platform
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	const doc = `{"statusCode":200,"body":{"items":[{"id":"a"},{"id":"b"}],"ok":true,"empty":null}}`
+
+	cases := []struct {
+		path  string
+		want  string
+		valid bool
+	}{
+		{"$", `{"body":{"empty":null,"items":[{"id":"a"},{"id":"b"}],"ok":true},"statusCode":200}`, true},
+		{"$.statusCode", `200`, true},
+		{"$.body.ok", `true`, true},
+		{"$.body.empty", `null`, true},
+		{"$.body.items[1].id", `"b"`, true},
+		{"$.body.items[0]", `{"id":"a"}`, true},
+		{".statusCode", `200`, true},
+
+		{"$.missing", "", false},
+		{"$.statusCode.value", "", false},
+		{"$.body.items[2]", "", false},
+		{"$.body.items[-1]", "", false},
+		{"$.body.items[x]", "", false},
+		{"$.body.items[0", "", false},
+		{"$.body[0]", "", false},
+		{"$body", "", false},
+	}
+
+	var resp interface{}
+	if err := json.Unmarshal([]byte(doc), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		v, err := jsonPath(resp, c.path)
+		if !c.valid {
+			if err == nil {
+				t.Errorf("jsonPath(%q) = %v, want an error", c.path, v)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("jsonPath(%q): %s", c.path, err)
+			continue
+		}
+
+		got, _ := json.Marshal(v)
+		if string(got) != c.want {
+			t.Errorf("jsonPath(%q) = %s, want %s", c.path, got, c.want)
+		}
+	}
+}
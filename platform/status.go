@@ -0,0 +1,240 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	sdk "github.com/hashicorp/waypoint-plugin-sdk/proto/gen"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/hashicorp/waypoint/builtin/aws/utils"
+	"github.com/pkg/errors"
+)
+
+// The window of CloudWatch metrics a status report covers.
+const statusWindow = 15 * time.Minute
+
+// Implement Status
+func (p *Platform) StatusFunc() interface{} {
+	return p.status
+}
+
+func (p *Platform) status(
+	ctx context.Context,
+	log hclog.Logger,
+	src *component.Source,
+	deployment *Deployment,
+	ui terminal.UI,
+) (*sdk.StatusReport, error) {
+	sg := ui.StepGroup()
+	defer sg.Wait()
+
+	step := sg.Add("Checking status of Lambda function version %s", deployment.Version)
+	defer step.Abort()
+
+	region := deployment.Region
+	if region == "" {
+		region = p.config.Region
+	}
+
+	sess, err := utils.GetSession(&utils.SessionConfig{
+		Region: region,
+		Logger: log,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lamSvc := lambda.New(sess)
+
+	cfg, err := lamSvc.GetFunctionConfigurationWithContext(ctx, &lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(deployment.FuncArn),
+		Qualifier:    aws.String(deployment.Version),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read function version %s", deployment.Version)
+	}
+
+	concurrency, err := lamSvc.GetFunctionConcurrencyWithContext(ctx, &lambda.GetFunctionConcurrencyInput{
+		FunctionName: aws.String(deployment.FuncArn),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read function concurrency")
+	}
+
+	sets, err := versionDimensions(ctx, lamSvc, src, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	cwSvc := cloudwatch.New(sess)
+
+	var m struct {
+		invocations, errors, throttles, duration float64
+	}
+
+	// The version's metrics are split between its own dimensions and those
+	// of the aliases routing to it, so they are summed across both
+	var totalDuration float64
+	for _, dimensions := range sets {
+		var invocations, duration float64
+
+		for _, q := range []struct {
+			name, stat string
+			value      *float64
+		}{
+			{"Invocations", cloudwatch.StatisticSum, &invocations},
+			{"Errors", cloudwatch.StatisticSum, &m.errors},
+			{"Throttles", cloudwatch.StatisticSum, &m.throttles},
+			{"Duration", cloudwatch.StatisticAverage, &duration},
+		} {
+			v, err := metric(ctx, cwSvc, "AWS/Lambda", q.name, q.stat, dimensions)
+			if err != nil {
+				return nil, err
+			}
+
+			if q.stat == cloudwatch.StatisticAverage {
+				*q.value = v
+			} else {
+				*q.value += v
+			}
+		}
+
+		m.invocations += invocations
+		totalDuration += duration * invocations
+	}
+
+	if m.invocations > 0 {
+		m.duration = totalDuration / m.invocations
+	}
+
+	state := aws.StringValue(cfg.State)
+	updateStatus := aws.StringValue(cfg.LastUpdateStatus)
+
+	reserved := "unreserved"
+	if concurrency.ReservedConcurrentExecutions != nil {
+		reserved = fmt.Sprintf("%d reserved", *concurrency.ReservedConcurrentExecutions)
+	}
+
+	details := []string{
+		fmt.Sprintf("state %s", state),
+		fmt.Sprintf("last update %s", updateStatus),
+		fmt.Sprintf("concurrency %s", reserved),
+		fmt.Sprintf("%.0f invocations, %.0f errors, %.0f throttles, %.0fms average duration in the last %s",
+			m.invocations, m.errors, m.throttles, m.duration, statusWindow),
+	}
+
+	report := &sdk.StatusReport{
+		External: true,
+	}
+
+	switch {
+	case state == lambda.StateFailed || state == lambda.StateInactive:
+		report.Health = sdk.StatusReport_DOWN
+		details = append(details, aws.StringValue(cfg.StateReason))
+	case updateStatus == lambda.LastUpdateStatusFailed:
+		report.Health = sdk.StatusReport_DOWN
+		details = append(details, aws.StringValue(cfg.LastUpdateStatusReason))
+	case state == lambda.StatePending || updateStatus == lambda.LastUpdateStatusInProgress:
+		report.Health = sdk.StatusReport_ALIVE
+	case m.errors > 0 || m.throttles > 0:
+		// Degraded: the version is serving but not cleanly
+		report.Health = sdk.StatusReport_PARTIAL
+	default:
+		report.Health = sdk.StatusReport_READY
+	}
+
+	report.HealthMessage = strings.Join(details, "; ")
+
+	step.Update("Lambda function version %s is %s", deployment.Version, strings.ToLower(report.Health.String()))
+	step.Done()
+
+	return report, nil
+}
+
+// versionDimensions returns the sets of dimensions the version's metrics are
+// reported under. Invocations through an alias are reported against the
+// alias, with the version that ran in the ExecutedVersion dimension.
+func versionDimensions(
+	ctx context.Context,
+	lamSvc *lambda.Lambda,
+	src *component.Source,
+	deployment *Deployment,
+) ([][]*cloudwatch.Dimension, error) {
+	sets := [][]*cloudwatch.Dimension{{
+		{Name: aws.String("FunctionName"), Value: aws.String(src.App)},
+		{Name: aws.String("Resource"), Value: aws.String(src.App + ":" + deployment.Version)},
+	}}
+
+	err := lamSvc.ListAliasesPagesWithContext(ctx, &lambda.ListAliasesInput{
+		FunctionName: aws.String(deployment.FuncArn),
+	}, func(out *lambda.ListAliasesOutput, lastPage bool) bool {
+		for _, a := range out.Aliases {
+			routed := aws.StringValue(a.FunctionVersion) == deployment.Version
+			if a.RoutingConfig != nil {
+				if _, ok := a.RoutingConfig.AdditionalVersionWeights[deployment.Version]; ok {
+					routed = true
+				}
+			}
+
+			if !routed {
+				continue
+			}
+
+			sets = append(sets, []*cloudwatch.Dimension{
+				{Name: aws.String("FunctionName"), Value: aws.String(src.App)},
+				{Name: aws.String("Resource"), Value: aws.String(src.App + ":" + aws.StringValue(a.Name))},
+				{Name: aws.String("ExecutedVersion"), Value: aws.String(deployment.Version)},
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list aliases of version %s", deployment.Version)
+	}
+
+	return sets, nil
+}
+
+// metric returns a single statistic for a CloudWatch metric over the status
+// window.
+func metric(
+	ctx context.Context,
+	cwSvc *cloudwatch.CloudWatch,
+	namespace, name, stat string,
+	dimensions []*cloudwatch.Dimension,
+) (float64, error) {
+	now := time.Now()
+
+	out, err := cwSvc.GetMetricStatisticsWithContext(ctx, &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String(namespace),
+		MetricName: aws.String(name),
+		Dimensions: dimensions,
+		StartTime:  aws.Time(now.Add(-statusWindow)),
+		EndTime:    aws.Time(now),
+		Period:     aws.Int64(int64(statusWindow / time.Second)),
+		Statistics: []*string{aws.String(stat)},
+	})
+
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to read metric %s", name)
+	}
+
+	var v float64
+	for _, dp := range out.Datapoints {
+		switch stat {
+		case cloudwatch.StatisticAverage:
+			v = aws.Float64Value(dp.Average)
+		default:
+			v += aws.Float64Value(dp.Sum)
+		}
+	}
+
+	return v, nil
+}
@@ -0,0 +1,132 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/pkg/errors"
+)
+
+const (
+	// How long to wait for the function to settle after each change.
+	DefaultWaitTimeout = 5 * time.Minute
+
+	// Bounds of the exponential backoff between polls.
+	minPollInterval = time.Second
+	maxPollInterval = 15 * time.Second
+)
+
+// errRetry is returned by a poll function to be called again after a backoff.
+var errRetry = errors.New("retry")
+
+// poll calls fn with exponential backoff and jitter until it returns
+// something other than errRetry, timeout elapses, or ctx is cancelled. what
+// describes the wait for error messages.
+func poll(ctx context.Context, timeout time.Duration, what string, fn func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	interval := minPollInterval
+
+	for {
+		err := fn(ctx)
+		if err != errRetry {
+			// A call interrupted by the deadline or cancellation reports it
+			// as such rather than as an AWS error
+			if err != nil && ctx.Err() != nil {
+				return waitError(ctx, timeout, what)
+			}
+			return err
+		}
+
+		// Sleep somewhere between half and all of the current interval
+		sleep := interval/2 + time.Duration(rand.Int63n(int64(interval/2)+1))
+
+		select {
+		case <-ctx.Done():
+			return waitError(ctx, timeout, what)
+		case <-time.After(sleep):
+		}
+
+		interval *= 2
+		if interval > maxPollInterval {
+			interval = maxPollInterval
+		}
+	}
+}
+
+func waitError(ctx context.Context, timeout time.Duration, what string) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s waiting for %s", timeout, what)
+	}
+	return errors.Wrapf(ctx.Err(), "cancelled while waiting for %s", what)
+}
+
+// isCode reports whether err is an AWS error with the given code.
+func isCode(err error, code string) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == code
+}
+
+// waitTimeout returns the configured wait timeout.
+func (p *Platform) waitTimeout() time.Duration {
+	if p.config.WaitTimeout == "" {
+		return DefaultWaitTimeout
+	}
+
+	d, _ := time.ParseDuration(p.config.WaitTimeout)
+	return d
+}
+
+// waitForFunction waits until the function is no longer Pending and its last
+// update has completed, surfacing the reason Lambda gives if either fails.
+func (p *Platform) waitForFunction(
+	ctx context.Context,
+	lamSvc *lambda.Lambda,
+	name string,
+) (*lambda.FunctionConfiguration, error) {
+	var cfg *lambda.FunctionConfiguration
+
+	err := poll(ctx, p.waitTimeout(), "function "+name+" to become active", func(ctx context.Context) error {
+		var err error
+		cfg, err = lamSvc.GetFunctionConfigurationWithContext(ctx, &lambda.GetFunctionConfigurationInput{
+			FunctionName: aws.String(name),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "unable to read function %s", name)
+		}
+
+		return functionSettled(name, cfg)
+	})
+
+	return cfg, err
+}
+
+// functionSettled returns nil if the function can be updated or published,
+// errRetry if it is still changing, or why it can't be used. An Inactive
+// function, one Lambda idled after weeks without invocations, counts as
+// settled since the next update reactivates it.
+func functionSettled(name string, cfg *lambda.FunctionConfiguration) error {
+	switch aws.StringValue(cfg.State) {
+	case lambda.StateFailed:
+		return fmt.Errorf("function %s is %s: %s",
+			name, aws.StringValue(cfg.State), aws.StringValue(cfg.StateReason))
+	case lambda.StatePending:
+		return errRetry
+	}
+
+	switch aws.StringValue(cfg.LastUpdateStatus) {
+	case lambda.LastUpdateStatusFailed:
+		return fmt.Errorf("update of function %s failed: %s",
+			name, aws.StringValue(cfg.LastUpdateStatusReason))
+	case lambda.LastUpdateStatusInProgress:
+		return errRetry
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+func TestFunctionSettled(t *testing.T) {
+	cases := []struct {
+		name   string
+		state  string
+		update string
+		want   string
+	}{
+		{"active", lambda.StateActive, lambda.LastUpdateStatusSuccessful, "settled"},
+		{"active without update", lambda.StateActive, "", "settled"},
+		{"inactive", lambda.StateInactive, lambda.LastUpdateStatusSuccessful, "settled"},
+		{"pending", lambda.StatePending, "", "retry"},
+		{"updating", lambda.StateActive, lambda.LastUpdateStatusInProgress, "retry"},
+		{"reactivating", lambda.StateInactive, lambda.LastUpdateStatusInProgress, "retry"},
+		{"failed", lambda.StateFailed, "", "error"},
+		{"update failed", lambda.StateActive, lambda.LastUpdateStatusFailed, "error"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := functionSettled("app", &lambda.FunctionConfiguration{
+				State:            aws.String(c.state),
+				LastUpdateStatus: aws.String(c.update),
+			})
+
+			got := "error"
+			switch err {
+			case nil:
+				got = "settled"
+			case errRetry:
+				got = "retry"
+			}
+
+			if got != c.want {
+				t.Errorf("state %q, last update %q: got %s (%v), want %s", c.state, c.update, got, err, c.want)
+			}
+		})
+	}
+}
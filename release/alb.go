@@ -0,0 +1,475 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/pkg/errors"
+)
+
+type ALBConfig struct {
+	// ListenerArn is the load balancer listener serving the function.
+	ListenerArn string `hcl:"listener_arn"`
+
+	// TargetGroupArn is an existing Lambda target group to register the
+	// function with. When not set, the plugin creates one for the app.
+	TargetGroupArn string `hcl:"target_group_arn,optional"`
+
+	// RuleArn is an existing listener rule to forward to the target group.
+	RuleArn string `hcl:"rule_arn,optional"`
+
+	// Paths, Hosts and Priority describe the listener rule the plugin
+	// creates when rule_arn is not set, such as ["/orders/*"].
+	Paths    []string `hcl:"paths,optional"`
+	Hosts    []string `hcl:"hosts,optional"`
+	Priority int64    `hcl:"priority,optional"`
+}
+
+// The most characters ELB allows in a target group name.
+const maxTargetGroupName = 32
+
+func (c *ALBConfig) validate() error {
+	if !strings.Contains(c.ListenerArn, ":elasticloadbalancing:") || !strings.Contains(c.ListenerArn, ":listener/") {
+		return fmt.Errorf("alb listener_arn %q is not a load balancer listener ARN", c.ListenerArn)
+	}
+
+	if c.TargetGroupArn != "" && !strings.Contains(c.TargetGroupArn, ":targetgroup/") {
+		return fmt.Errorf("alb target_group_arn %q is not a target group ARN", c.TargetGroupArn)
+	}
+
+	if c.RuleArn != "" {
+		if len(c.Paths) > 0 || len(c.Hosts) > 0 || c.Priority != 0 {
+			return fmt.Errorf("alb rule_arn can't be combined with paths, hosts or priority")
+		}
+		return nil
+	}
+
+	if len(c.Paths) == 0 && len(c.Hosts) == 0 {
+		return fmt.Errorf("alb requires rule_arn, or paths or hosts for a new listener rule")
+	}
+
+	if c.Priority < 1 || c.Priority > 50000 {
+		return fmt.Errorf("alb priority must be between 1 and 50000")
+	}
+
+	return nil
+}
+
+// targetGroupName is the name of the target group the plugin creates for an
+// app.
+func targetGroupName(src *component.Source) string {
+	name := "waypoint-" + src.App
+	if len(name) > maxTargetGroupName {
+		name = name[:maxTargetGroupName]
+	}
+	return strings.TrimRight(name, "-")
+}
+
+// albStatementId is the ID of the permission letting the target group invoke
+// the function.
+func albStatementId(targetGroupArn string) string {
+	// arn:...:targetgroup/<name>/<id>
+	parts := strings.Split(targetGroupArn, "/")
+	return fmt.Sprintf("lambda-alb-%s", parts[len(parts)-2])
+}
+
+// conditions are the conditions of the listener rule the plugin creates.
+func (c *ALBConfig) conditions() []*elbv2.RuleCondition {
+	var conditions []*elbv2.RuleCondition
+
+	if len(c.Paths) > 0 {
+		conditions = append(conditions, &elbv2.RuleCondition{
+			Field:             aws.String("path-pattern"),
+			PathPatternConfig: &elbv2.PathPatternConditionConfig{Values: aws.StringSlice(c.Paths)},
+		})
+	}
+
+	if len(c.Hosts) > 0 {
+		conditions = append(conditions, &elbv2.RuleCondition{
+			Field:            aws.String("host-header"),
+			HostHeaderConfig: &elbv2.HostHeaderConditionConfig{Values: aws.StringSlice(c.Hosts)},
+		})
+	}
+
+	return conditions
+}
+
+// albRelease is a load balancer target group being released.
+type albRelease struct {
+	*ALBConfig
+
+	targetGroupArn     string
+	managedTargetGroup bool
+
+	ruleArn     string
+	managedRule bool
+
+	endpoint string
+
+	// What the target group invoked before the release, empty if nothing.
+	previousArn string
+}
+
+// releaseALB registers arn with the target group, creating the group if
+// needed, and points the listener rule at it.
+func (rm *ReleaseManager) releaseALB(
+	ctx context.Context,
+	lamSvc *lambda.Lambda,
+	elbSvc *elbv2.ELBV2,
+	src *component.Source,
+	arn string,
+) (*albRelease, error) {
+	a := &albRelease{
+		ALBConfig:      rm.config.ALB,
+		targetGroupArn: rm.config.ALB.TargetGroupArn,
+		ruleArn:        rm.config.ALB.RuleArn,
+		endpoint:       arn,
+	}
+
+	if a.targetGroupArn == "" {
+		if err := a.ensureTargetGroup(ctx, elbSvc, src); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err := lamSvc.AddPermissionWithContext(ctx, &lambda.AddPermissionInput{
+		StatementId:  aws.String(albStatementId(a.targetGroupArn)),
+		FunctionName: aws.String(arn),
+		Action:       aws.String("lambda:InvokeFunction"),
+		Principal:    aws.String("elasticloadbalancing.amazonaws.com"),
+		SourceArn:    aws.String(a.targetGroupArn),
+	})
+
+	if err != nil && !isCode(err, lambda.ErrCodeResourceConflictException) {
+		return nil, errors.Wrapf(err, "unable to let target group %s invoke the function", a.targetGroupArn)
+	}
+	granted := err == nil
+
+	a.previousArn, err = registeredTarget(ctx, elbSvc, a.targetGroupArn)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.previousArn != arn {
+		if err := register(ctx, elbSvc, a.targetGroupArn, a.previousArn, arn); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := a.ensureRule(ctx, elbSvc, src); err != nil {
+		// Without its rule the release is abandoned, so the target group
+		// goes back to what it invoked before
+		if rerr := a.restore(ctx, elbSvc); rerr != nil {
+			return nil, errors.Wrapf(err, "listener rule failed and target group %s could not be restored (%s)", a.targetGroupArn, rerr)
+		}
+
+		if granted {
+			if rerr := removeStatement(ctx, lamSvc, arn, albStatementId(a.targetGroupArn)); rerr != nil {
+				return nil, errors.Wrapf(err, "listener rule failed and the permission of %s could not be removed (%s)", a.targetGroupArn, rerr)
+			}
+		}
+
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// ensureTargetGroup finds or creates the app's Lambda target group.
+func (a *albRelease) ensureTargetGroup(ctx context.Context, elbSvc *elbv2.ELBV2, src *component.Source) error {
+	name := targetGroupName(src)
+
+	out, err := elbSvc.DescribeTargetGroupsWithContext(ctx, &elbv2.DescribeTargetGroupsInput{
+		Names: []*string{aws.String(name)},
+	})
+
+	switch {
+	case err == nil && len(out.TargetGroups) > 0:
+		a.targetGroupArn = aws.StringValue(out.TargetGroups[0].TargetGroupArn)
+		a.managedTargetGroup, err = hasAppTag(ctx, elbSvc, src, a.targetGroupArn)
+		return err
+	case err != nil && !isCode(err, elbv2.ErrCodeTargetGroupNotFoundException):
+		return errors.Wrapf(err, "unable to read target group %s", name)
+	}
+
+	created, err := elbSvc.CreateTargetGroupWithContext(ctx, &elbv2.CreateTargetGroupInput{
+		Name:       aws.String(name),
+		TargetType: aws.String(elbv2.TargetTypeEnumLambda),
+		Tags:       []*elbv2.Tag{{Key: aws.String("waypoint.app"), Value: aws.String(src.App)}},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to create target group %s", name)
+	}
+
+	a.targetGroupArn = aws.StringValue(created.TargetGroups[0].TargetGroupArn)
+	a.managedTargetGroup = true
+
+	return nil
+}
+
+// ensureRule points the configured listener rule at the target group, or
+// creates or updates the app's own rule. An untagged rule already forwarding
+// to the target group is used as it is.
+func (a *albRelease) ensureRule(ctx context.Context, elbSvc *elbv2.ELBV2, src *component.Source) error {
+	forward := []*elbv2.Action{{
+		Type:           aws.String(elbv2.ActionTypeEnumForward),
+		TargetGroupArn: aws.String(a.targetGroupArn),
+	}}
+
+	if a.ruleArn != "" {
+		_, err := elbSvc.ModifyRuleWithContext(ctx, &elbv2.ModifyRuleInput{
+			RuleArn: aws.String(a.ruleArn),
+			Actions: forward,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "unable to update listener rule %s", a.ruleArn)
+		}
+		return nil
+	}
+
+	rules, err := listenerRules(ctx, elbSvc, a.ListenerArn)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rules {
+		for _, action := range r.Actions {
+			if aws.StringValue(action.TargetGroupArn) != a.targetGroupArn {
+				continue
+			}
+
+			a.ruleArn = aws.StringValue(r.RuleArn)
+			a.managedRule, err = hasAppTag(ctx, elbSvc, src, a.ruleArn)
+			if err != nil {
+				return err
+			}
+
+			// A rule the plugin didn't create already forwards to the
+			// target group and is left as it is
+			if !a.managedRule {
+				return nil
+			}
+
+			_, err = elbSvc.ModifyRuleWithContext(ctx, &elbv2.ModifyRuleInput{
+				RuleArn:    r.RuleArn,
+				Conditions: a.conditions(),
+				Actions:    forward,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "unable to update listener rule %s", a.ruleArn)
+			}
+
+			if aws.StringValue(r.Priority) == strconv.FormatInt(a.Priority, 10) {
+				return nil
+			}
+
+			_, err = elbSvc.SetRulePrioritiesWithContext(ctx, &elbv2.SetRulePrioritiesInput{
+				RulePriorities: []*elbv2.RulePriorityPair{{
+					RuleArn:  r.RuleArn,
+					Priority: aws.Int64(a.Priority),
+				}},
+			})
+			if err != nil {
+				return errors.Wrapf(err, "unable to set priority of listener rule %s to %d", a.ruleArn, a.Priority)
+			}
+			return nil
+		}
+	}
+
+	out, err := elbSvc.CreateRuleWithContext(ctx, &elbv2.CreateRuleInput{
+		ListenerArn: aws.String(a.ListenerArn),
+		Priority:    aws.Int64(a.Priority),
+		Conditions:  a.conditions(),
+		Actions:     forward,
+		Tags:        []*elbv2.Tag{{Key: aws.String("waypoint.app"), Value: aws.String(src.App)}},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to create listener rule with priority %d", a.Priority)
+	}
+
+	a.ruleArn = aws.StringValue(out.Rules[0].RuleArn)
+	a.managedRule = true
+
+	return nil
+}
+
+// url returns the URL the listener serves the function from.
+func (a *albRelease) url(ctx context.Context, elbSvc *elbv2.ELBV2) (string, error) {
+	listeners, err := elbSvc.DescribeListenersWithContext(ctx, &elbv2.DescribeListenersInput{
+		ListenerArns: []*string{aws.String(a.ListenerArn)},
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to read listener %s", a.ListenerArn)
+	}
+	if len(listeners.Listeners) == 0 {
+		return "", fmt.Errorf("listener %s does not exist", a.ListenerArn)
+	}
+
+	listener := listeners.Listeners[0]
+
+	var host string
+	if len(a.Hosts) > 0 && !strings.ContainsAny(a.Hosts[0], "*?") {
+		host = a.Hosts[0]
+	} else {
+		lbs, err := elbSvc.DescribeLoadBalancersWithContext(ctx, &elbv2.DescribeLoadBalancersInput{
+			LoadBalancerArns: []*string{listener.LoadBalancerArn},
+		})
+		if err != nil {
+			return "", errors.Wrapf(err, "unable to read load balancer %s", aws.StringValue(listener.LoadBalancerArn))
+		}
+		if len(lbs.LoadBalancers) == 0 {
+			return "", fmt.Errorf("load balancer %s does not exist", aws.StringValue(listener.LoadBalancerArn))
+		}
+		host = aws.StringValue(lbs.LoadBalancers[0].DNSName)
+	}
+
+	scheme := strings.ToLower(aws.StringValue(listener.Protocol))
+	port := aws.Int64Value(listener.Port)
+
+	url := scheme + "://" + host
+	if (scheme == "http" && port != 80) || (scheme == "https" && port != 443) {
+		url += ":" + strconv.FormatInt(port, 10)
+	}
+
+	if len(a.Paths) > 0 {
+		url += strings.TrimRight(a.Paths[0], "*")
+	}
+
+	return url, nil
+}
+
+// restore registers what the target group invoked before the release in
+// place of the released version.
+func (a *albRelease) restore(ctx context.Context, elbSvc *elbv2.ELBV2) error {
+	if a.previousArn == a.endpoint {
+		return nil
+	}
+	return register(ctx, elbSvc, a.targetGroupArn, a.endpoint, a.previousArn)
+}
+
+// registeredTarget returns the function registered with the target group, or
+// an empty string if there is none.
+func registeredTarget(ctx context.Context, elbSvc *elbv2.ELBV2, targetGroupArn string) (string, error) {
+	out, err := elbSvc.DescribeTargetHealthWithContext(ctx, &elbv2.DescribeTargetHealthInput{
+		TargetGroupArn: aws.String(targetGroupArn),
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to read targets of %s", targetGroupArn)
+	}
+
+	for _, t := range out.TargetHealthDescriptions {
+		return aws.StringValue(t.Target.Id), nil
+	}
+
+	return "", nil
+}
+
+// register swaps the function registered with the target group from one ARN
+// to another. A Lambda target group holds a single function, so the old one
+// is deregistered first.
+func register(ctx context.Context, elbSvc *elbv2.ELBV2, targetGroupArn, from, to string) error {
+	if from != "" {
+		_, err := elbSvc.DeregisterTargetsWithContext(ctx, &elbv2.DeregisterTargetsInput{
+			TargetGroupArn: aws.String(targetGroupArn),
+			Targets:        []*elbv2.TargetDescription{{Id: aws.String(from)}},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "unable to deregister %s from %s", from, targetGroupArn)
+		}
+	}
+
+	if to == "" {
+		return nil
+	}
+
+	_, err := elbSvc.RegisterTargetsWithContext(ctx, &elbv2.RegisterTargetsInput{
+		TargetGroupArn: aws.String(targetGroupArn),
+		Targets:        []*elbv2.TargetDescription{{Id: aws.String(to)}},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to register %s with %s", to, targetGroupArn)
+	}
+
+	return nil
+}
+
+// listenerRules returns every rule of the listener.
+func listenerRules(ctx context.Context, elbSvc *elbv2.ELBV2, listenerArn string) ([]*elbv2.Rule, error) {
+	var rules []*elbv2.Rule
+
+	input := &elbv2.DescribeRulesInput{ListenerArn: aws.String(listenerArn)}
+	for {
+		out, err := elbSvc.DescribeRulesWithContext(ctx, input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to list rules of listener %s", listenerArn)
+		}
+
+		rules = append(rules, out.Rules...)
+
+		if out.NextMarker == nil {
+			return rules, nil
+		}
+		input.Marker = out.NextMarker
+	}
+}
+
+// hasAppTag reports whether the resource was created by the plugin for the
+// app.
+func hasAppTag(ctx context.Context, elbSvc *elbv2.ELBV2, src *component.Source, arn string) (bool, error) {
+	out, err := elbSvc.DescribeTagsWithContext(ctx, &elbv2.DescribeTagsInput{
+		ResourceArns: []*string{aws.String(arn)},
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to read tags of %s", arn)
+	}
+
+	for _, d := range out.TagDescriptions {
+		for _, t := range d.Tags {
+			if aws.StringValue(t.Key) == "waypoint.app" && aws.StringValue(t.Value) == src.App {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// deleteALB deregisters the function from the target group and deletes the
+// rule and target group if the plugin created them.
+func deleteALB(ctx context.Context, elbSvc *elbv2.ELBV2, alb *AlbTarget) error {
+	_, err := elbSvc.DeregisterTargetsWithContext(ctx, &elbv2.DeregisterTargetsInput{
+		TargetGroupArn: aws.String(alb.TargetGroupArn),
+		Targets:        []*elbv2.TargetDescription{{Id: aws.String(alb.FunctionArn)}},
+	})
+	if err != nil && !isCode(err, elbv2.ErrCodeTargetGroupNotFoundException) && !isCode(err, elbv2.ErrCodeInvalidTargetException) {
+		return errors.Wrapf(err, "unable to deregister %s from %s", alb.FunctionArn, alb.TargetGroupArn)
+	}
+
+	if alb.ManagedRule {
+		_, err = elbSvc.DeleteRuleWithContext(ctx, &elbv2.DeleteRuleInput{
+			RuleArn: aws.String(alb.RuleArn),
+		})
+		if err != nil && !isCode(err, elbv2.ErrCodeRuleNotFoundException) {
+			return errors.Wrapf(err, "unable to delete listener rule %s", alb.RuleArn)
+		}
+	}
+
+	if alb.ManagedTargetGroup {
+		// A target group still used by a rule the plugin didn't create is
+		// left in place
+		_, err = elbSvc.DeleteTargetGroupWithContext(ctx, &elbv2.DeleteTargetGroupInput{
+			TargetGroupArn: aws.String(alb.TargetGroupArn),
+		})
+		if err != nil && !isCode(err, elbv2.ErrCodeTargetGroupNotFoundException) && !isCode(err, elbv2.ErrCodeResourceInUseException) {
+			return errors.Wrapf(err, "unable to delete target group %s", alb.TargetGroupArn)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package release
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/phoban01/lambda-ext/platform"
+	"github.com/pkg/errors"
+)
+
+// ensureAlias reads the configured alias, creating it against the deployed
+// version if it doesn't exist yet. An existing alias is left pointing at the
+// version it currently serves so that traffic can be shifted gradually.
+func (rm *ReleaseManager) ensureAlias(
+	ctx context.Context,
+	lamSvc *lambda.Lambda,
+	deploy *platform.Deployment,
+) (*lambda.AliasConfiguration, error) {
+	alias, err := lamSvc.GetAliasWithContext(ctx, &lambda.GetAliasInput{
+		FunctionName: aws.String(deploy.FuncArn),
+		Name:         aws.String(rm.config.Alias.Name),
+	})
+
+	if err == nil {
+		return alias, nil
+	}
+
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != lambda.ErrCodeResourceNotFoundException {
+		return nil, errors.Wrapf(err, "unable to read alias %s", rm.config.Alias.Name)
+	}
+
+	alias, err = lamSvc.CreateAliasWithContext(ctx, &lambda.CreateAliasInput{
+		FunctionName:    aws.String(deploy.FuncArn),
+		FunctionVersion: aws.String(deploy.Version),
+		Name:            aws.String(rm.config.Alias.Name),
+		Description:     aws.String("managed by waypoint"),
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create alias %s", rm.config.Alias.Name)
+	}
+
+	return alias, nil
+}
+
+// shiftTraffic moves the alias from the version it currently serves onto the
+// deployed version, one configured weight at a time, baking for the configured
+// interval between each step while watching for alarms.
+func (rm *ReleaseManager) shiftTraffic(
+	ctx context.Context,
+	sg terminal.StepGroup,
+	lamSvc *lambda.Lambda,
+	watcher *alarmWatcher,
+	alias *lambda.AliasConfiguration,
+	deploy *platform.Deployment,
+) error {
+	version := deploy.Version
+	prev := aws.StringValue(alias.FunctionVersion)
+
+	// A freshly created alias, or a re-release of the same version, has
+	// nothing to shift.
+	if prev == version {
+		return nil
+	}
+
+	interval := DefaultShiftInterval
+	if rm.config.Alias.Interval != "" {
+		interval, _ = time.ParseDuration(rm.config.Alias.Interval)
+	}
+
+	var step terminal.Step
+
+	// We put this in a function because step is reassigned on every weight,
+	// and only the last one may still need aborting.
+	defer func() {
+		if step != nil {
+			step.Abort()
+		}
+	}()
+
+	for _, w := range rm.config.Alias.Weights {
+		step = sg.Add("Shifting %d%% of traffic to version %s", w, version)
+
+		input := &lambda.UpdateAliasInput{
+			FunctionName: aws.String(deploy.FuncArn),
+			Name:         alias.Name,
+		}
+
+		if w == 100 {
+			// Promote the new version and clear the routing config
+			input.FunctionVersion = aws.String(version)
+			input.RoutingConfig = &lambda.AliasRoutingConfiguration{
+				AdditionalVersionWeights: map[string]*float64{},
+			}
+		} else {
+			input.FunctionVersion = aws.String(prev)
+			input.RoutingConfig = &lambda.AliasRoutingConfiguration{
+				AdditionalVersionWeights: map[string]*float64{
+					version: aws.Float64(float64(w) / 100),
+				},
+			}
+		}
+
+		_, err := lamSvc.UpdateAliasWithContext(ctx, input)
+		if err != nil {
+			return errors.Wrapf(err, "unable to shift traffic on alias %s", *alias.Name)
+		}
+
+		if w < 100 {
+			step.Update("Baking %d%% of traffic on version %s for %s", w, version, interval)
+
+			if err := watcher.wait(ctx, interval); err != nil {
+				return err
+			}
+		}
+
+		step.Update("Shifted %d%% of traffic to version %s", w, version)
+		step.Done()
+	}
+
+	return nil
+}
@@ -0,0 +1,372 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/phoban01/lambda-ext/platform"
+	"github.com/pkg/errors"
+)
+
+// How often a CodeDeploy deployment is polled for progress.
+const codeDeployPollInterval = 10 * time.Second
+
+// deploymentConfigName expands the short names of the predefined Lambda
+// deployment configs, leaving fully qualified names untouched.
+func deploymentConfigName(name string) string {
+	if strings.Contains(name, ".") {
+		return name
+	}
+
+	return "CodeDeployDefault.Lambda" + name
+}
+
+// codeDeploy shifts the alias onto the deployed version with a CodeDeploy
+// deployment, streaming its lifecycle events into sg, and returns the
+// deployment ID.
+func (rm *ReleaseManager) codeDeploy(
+	ctx context.Context,
+	sg terminal.StepGroup,
+	cdSvc *codedeploy.CodeDeploy,
+	src *component.Source,
+	alias *lambda.AliasConfiguration,
+	deploy *platform.Deployment,
+) (string, error) {
+	cfg := rm.config.CodeDeploy
+	prev := aws.StringValue(alias.FunctionVersion)
+
+	// A freshly created alias, or a re-release of the same version, has
+	// nothing to shift.
+	if prev == deploy.Version {
+		return "", nil
+	}
+
+	app := cfg.Application
+	if app == "" {
+		app = src.App
+	}
+
+	group := cfg.DeploymentGroup
+	if group == "" {
+		group = src.App
+	}
+
+	configName := deploymentConfigName(cfg.DeploymentConfig)
+
+	step := sg.Add("Preparing CodeDeploy deployment group: %s/%s", app, group)
+	defer func() {
+		step.Abort()
+	}()
+
+	err := rm.ensureDeploymentGroup(ctx, cdSvc, app, group, configName)
+	if err != nil {
+		return "", err
+	}
+
+	step.Done()
+
+	step = sg.Add("Creating CodeDeploy deployment (%s)", configName)
+
+	appSpec, err := rm.appSpec(src, alias, deploy)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := cdSvc.CreateDeploymentWithContext(ctx, &codedeploy.CreateDeploymentInput{
+		ApplicationName:      aws.String(app),
+		DeploymentGroupName:  aws.String(group),
+		DeploymentConfigName: aws.String(configName),
+		Description:          aws.String(fmt.Sprintf("waypoint %s version %s", src.App, deploy.Version)),
+		Revision: &codedeploy.RevisionLocation{
+			RevisionType: aws.String(codedeploy.RevisionLocationTypeAppSpecContent),
+			AppSpecContent: &codedeploy.AppSpecContent{
+				Content: aws.String(appSpec),
+			},
+		},
+	})
+
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to create CodeDeploy deployment")
+	}
+
+	id := *out.DeploymentId
+
+	step.Update("Created CodeDeploy deployment: %s", id)
+	step.Done()
+
+	return id, rm.waitDeployment(ctx, sg, cdSvc, id)
+}
+
+// ensureDeploymentGroup creates the CodeDeploy application and deployment
+// group if they don't exist, and keeps the group's config up to date if they
+// do.
+func (rm *ReleaseManager) ensureDeploymentGroup(
+	ctx context.Context,
+	cdSvc *codedeploy.CodeDeploy,
+	app, group, configName string,
+) error {
+	cfg := rm.config.CodeDeploy
+
+	_, err := cdSvc.GetApplicationWithContext(ctx, &codedeploy.GetApplicationInput{
+		ApplicationName: aws.String(app),
+	})
+
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != codedeploy.ErrCodeApplicationDoesNotExistException {
+			return errors.Wrapf(err, "unable to read CodeDeploy application %s", app)
+		}
+
+		_, err = cdSvc.CreateApplicationWithContext(ctx, &codedeploy.CreateApplicationInput{
+			ApplicationName: aws.String(app),
+			ComputePlatform: aws.String(codedeploy.ComputePlatformLambda),
+		})
+
+		if err != nil {
+			return errors.Wrapf(err, "unable to create CodeDeploy application %s", app)
+		}
+	}
+
+	cur, err := cdSvc.GetDeploymentGroupWithContext(ctx, &codedeploy.GetDeploymentGroupInput{
+		ApplicationName:     aws.String(app),
+		DeploymentGroupName: aws.String(group),
+	})
+
+	if err == nil {
+		if aws.StringValue(cur.DeploymentGroupInfo.DeploymentConfigName) == configName {
+			return nil
+		}
+
+		_, err = cdSvc.UpdateDeploymentGroupWithContext(ctx, &codedeploy.UpdateDeploymentGroupInput{
+			ApplicationName:            aws.String(app),
+			CurrentDeploymentGroupName: aws.String(group),
+			DeploymentConfigName:       aws.String(configName),
+		})
+
+		if err != nil {
+			return errors.Wrapf(err, "unable to update CodeDeploy deployment group %s", group)
+		}
+
+		return nil
+	}
+
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != codedeploy.ErrCodeDeploymentGroupDoesNotExistException {
+		return errors.Wrapf(err, "unable to read CodeDeploy deployment group %s", group)
+	}
+
+	if cfg.ServiceRoleArn == "" {
+		return fmt.Errorf("code_deploy service_role_arn is required to create deployment group %s", group)
+	}
+
+	input := &codedeploy.CreateDeploymentGroupInput{
+		ApplicationName:      aws.String(app),
+		DeploymentGroupName:  aws.String(group),
+		DeploymentConfigName: aws.String(configName),
+		ServiceRoleArn:       aws.String(cfg.ServiceRoleArn),
+		DeploymentStyle: &codedeploy.DeploymentStyle{
+			DeploymentType:   aws.String(codedeploy.DeploymentTypeBlueGreen),
+			DeploymentOption: aws.String(codedeploy.DeploymentOptionWithTrafficControl),
+		},
+		AutoRollbackConfiguration: &codedeploy.AutoRollbackConfiguration{
+			Enabled: aws.Bool(true),
+			Events: aws.StringSlice([]string{
+				codedeploy.AutoRollbackEventDeploymentFailure,
+				codedeploy.AutoRollbackEventDeploymentStopOnAlarm,
+			}),
+		},
+	}
+
+	// Let CodeDeploy stop and roll back the deployment on the same alarms the
+	// release watches.
+	if rm.config.Rollback != nil && len(rm.config.Rollback.Alarms) > 0 {
+		input.AlarmConfiguration = &codedeploy.AlarmConfiguration{
+			Enabled: aws.Bool(true),
+		}
+
+		for _, a := range rm.config.Rollback.Alarms {
+			input.AlarmConfiguration.Alarms = append(input.AlarmConfiguration.Alarms, &codedeploy.Alarm{
+				Name: aws.String(a),
+			})
+		}
+	}
+
+	_, err = cdSvc.CreateDeploymentGroupWithContext(ctx, input)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create CodeDeploy deployment group %s", group)
+	}
+
+	return nil
+}
+
+// appSpec renders the AppSpec content that moves the alias from its current
+// version to the deployed one.
+func (rm *ReleaseManager) appSpec(
+	src *component.Source,
+	alias *lambda.AliasConfiguration,
+	deploy *platform.Deployment,
+) (string, error) {
+	cfg := rm.config.CodeDeploy
+
+	spec := map[string]interface{}{
+		"version": json.Number("0.0"),
+		"Resources": []interface{}{
+			map[string]interface{}{
+				src.App: map[string]interface{}{
+					"Type": "AWS::Lambda::Function",
+					"Properties": map[string]string{
+						"Name":           src.App,
+						"Alias":          aws.StringValue(alias.Name),
+						"CurrentVersion": aws.StringValue(alias.FunctionVersion),
+						"TargetVersion":  deploy.Version,
+					},
+				},
+			},
+		},
+	}
+
+	var hooks []interface{}
+	if cfg.PreTrafficHook != "" {
+		hooks = append(hooks, map[string]string{"BeforeAllowTraffic": cfg.PreTrafficHook})
+	}
+	if cfg.PostTrafficHook != "" {
+		hooks = append(hooks, map[string]string{"AfterAllowTraffic": cfg.PostTrafficHook})
+	}
+	if len(hooks) > 0 {
+		spec["Hooks"] = hooks
+	}
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// waitDeployment polls the deployment until it completes, adding a step to
+// sg for each lifecycle event as it starts.
+func (rm *ReleaseManager) waitDeployment(
+	ctx context.Context,
+	sg terminal.StepGroup,
+	cdSvc *codedeploy.CodeDeploy,
+	id string,
+) error {
+	steps := map[string]terminal.Step{}
+	finished := map[string]bool{}
+	defer func() {
+		for _, s := range steps {
+			s.Abort()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(codeDeployPollInterval):
+		}
+
+		err := rm.streamLifecycleEvents(ctx, sg, cdSvc, id, steps, finished)
+		if err != nil {
+			return err
+		}
+
+		out, err := cdSvc.GetDeploymentWithContext(ctx, &codedeploy.GetDeploymentInput{
+			DeploymentId: aws.String(id),
+		})
+
+		if err != nil {
+			return errors.Wrapf(err, "unable to read CodeDeploy deployment %s", id)
+		}
+
+		info := out.DeploymentInfo
+
+		switch aws.StringValue(info.Status) {
+		case codedeploy.DeploymentStatusSucceeded:
+			return nil
+		case codedeploy.DeploymentStatusFailed, codedeploy.DeploymentStatusStopped:
+			msg := "no error information"
+			if info.ErrorInformation != nil {
+				msg = aws.StringValue(info.ErrorInformation.Message)
+			}
+
+			return fmt.Errorf("CodeDeploy deployment %s %s: %s",
+				id, strings.ToLower(aws.StringValue(info.Status)), msg)
+		}
+	}
+}
+
+// streamLifecycleEvents reflects the state of each lifecycle event of the
+// deployment's Lambda target onto its own step. Events that reached a final
+// status are recorded in finished and left alone afterwards.
+func (rm *ReleaseManager) streamLifecycleEvents(
+	ctx context.Context,
+	sg terminal.StepGroup,
+	cdSvc *codedeploy.CodeDeploy,
+	id string,
+	steps map[string]terminal.Step,
+	finished map[string]bool,
+) error {
+	targets, err := cdSvc.ListDeploymentTargetsWithContext(ctx, &codedeploy.ListDeploymentTargetsInput{
+		DeploymentId: aws.String(id),
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "unable to list CodeDeploy deployment targets")
+	}
+
+	for _, targetId := range targets.TargetIds {
+		out, err := cdSvc.GetDeploymentTargetWithContext(ctx, &codedeploy.GetDeploymentTargetInput{
+			DeploymentId: aws.String(id),
+			TargetId:     targetId,
+		})
+
+		if err != nil {
+			return errors.Wrapf(err, "unable to read CodeDeploy deployment target")
+		}
+
+		if out.DeploymentTarget == nil || out.DeploymentTarget.LambdaTarget == nil {
+			continue
+		}
+
+		for _, ev := range out.DeploymentTarget.LambdaTarget.LifecycleEvents {
+			name := aws.StringValue(ev.LifecycleEventName)
+			status := aws.StringValue(ev.Status)
+
+			if status == codedeploy.LifecycleEventStatusPending || finished[name] {
+				continue
+			}
+
+			step, ok := steps[name]
+			if !ok {
+				step = sg.Add("CodeDeploy %s: %s", name, status)
+				steps[name] = step
+			}
+
+			step.Update("CodeDeploy %s: %s", name, status)
+
+			switch status {
+			case codedeploy.LifecycleEventStatusSucceeded, codedeploy.LifecycleEventStatusSkipped:
+				step.Done()
+			case codedeploy.LifecycleEventStatusFailed:
+				step.Status(terminal.StatusError)
+				step.Done()
+			default:
+				continue
+			}
+
+			finished[name] = true
+			delete(steps, name)
+		}
+	}
+
+	return nil
+}
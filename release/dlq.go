@@ -0,0 +1,143 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/pkg/errors"
+)
+
+type DeadLetterQueueConfig struct {
+	// Arn is an existing SQS queue to send undeliverable events to. Its
+	// policy must let events.amazonaws.com send messages. When not set, the
+	// plugin creates and manages a queue for the app.
+	Arn string `hcl:"arn,optional"`
+}
+
+// The bounds EventBridge puts on the retry policy of a target.
+const (
+	maxRetryAttempts = 185
+	minEventAge      = time.Minute
+	maxEventAge      = 24 * time.Hour
+)
+
+// How long the managed queue keeps undeliverable events, the SQS maximum.
+const deadLetterRetention = 14 * 24 * time.Hour
+
+func (c *ReleaseConfig) validateDelivery() error {
+	if c.MaxRetryAttempts != nil && (*c.MaxRetryAttempts < 0 || *c.MaxRetryAttempts > maxRetryAttempts) {
+		return fmt.Errorf("max_retry_attempts must be between 0 and %d", maxRetryAttempts)
+	}
+
+	if c.MaxEventAge != "" {
+		d, err := time.ParseDuration(c.MaxEventAge)
+		if err != nil {
+			return fmt.Errorf("invalid max_event_age %q: %s", c.MaxEventAge, err)
+		}
+
+		if d < minEventAge || d > maxEventAge {
+			return fmt.Errorf("max_event_age must be between %s and %s", minEventAge, maxEventAge)
+		}
+	}
+
+	return nil
+}
+
+// retryPolicy returns the retry policy for the targets, or nil to keep the
+// EventBridge defaults.
+func (rm *ReleaseManager) retryPolicy() *eventbridge.RetryPolicy {
+	if rm.config.MaxRetryAttempts == nil && rm.config.MaxEventAge == "" {
+		return nil
+	}
+
+	policy := &eventbridge.RetryPolicy{
+		MaximumRetryAttempts: rm.config.MaxRetryAttempts,
+	}
+
+	if rm.config.MaxEventAge != "" {
+		d, _ := time.ParseDuration(rm.config.MaxEventAge)
+		policy.MaximumEventAgeInSeconds = aws.Int64(int64(d / time.Second))
+	}
+
+	return policy
+}
+
+// deadLetterQueueName is the name of the queue the plugin manages for an app.
+func deadLetterQueueName(src *component.Source) string {
+	return fmt.Sprintf("waypoint-%s-dlq", src.App)
+}
+
+// ensureDeadLetterQueue creates the managed queue if needed and lets the
+// given rules send to it, returning its ARN and URL.
+func (rm *ReleaseManager) ensureDeadLetterQueue(
+	ctx context.Context,
+	sqsSvc *sqs.SQS,
+	src *component.Source,
+	ruleArns []string,
+) (string, string, error) {
+	name := deadLetterQueueName(src)
+
+	// Creating a queue that already exists with the same attributes returns
+	// the existing one
+	queue, err := sqsSvc.CreateQueueWithContext(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String(name),
+		Attributes: map[string]*string{
+			sqs.QueueAttributeNameMessageRetentionPeriod: aws.String(
+				strconv.Itoa(int(deadLetterRetention / time.Second))),
+		},
+		Tags: map[string]*string{
+			"waypoint.app": aws.String(src.App),
+		},
+	})
+	if err != nil {
+		return "", "", errors.Wrapf(err, "unable to create SQS queue %s", name)
+	}
+
+	attrs, err := sqsSvc.GetQueueAttributesWithContext(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       queue.QueueUrl,
+		AttributeNames: []*string{aws.String(sqs.QueueAttributeNameQueueArn)},
+	})
+	if err != nil {
+		return "", "", errors.Wrapf(err, "unable to read SQS queue %s", name)
+	}
+
+	arn := aws.StringValue(attrs.Attributes[sqs.QueueAttributeNameQueueArn])
+
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Sid":       "waypoint-eventbridge",
+				"Effect":    "Allow",
+				"Principal": map[string]string{"Service": "events.amazonaws.com"},
+				"Action":    "sqs:SendMessage",
+				"Resource":  arn,
+				"Condition": map[string]interface{}{
+					"ArnEquals": map[string]interface{}{"aws:SourceArn": ruleArns},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	_, err = sqsSvc.SetQueueAttributesWithContext(ctx, &sqs.SetQueueAttributesInput{
+		QueueUrl: queue.QueueUrl,
+		Attributes: map[string]*string{
+			sqs.QueueAttributeNamePolicy: aws.String(string(policy)),
+		},
+	})
+	if err != nil {
+		return "", "", errors.Wrapf(err, "unable to set the policy of SQS queue %s", name)
+	}
+
+	return arn, aws.StringValue(queue.QueueUrl), nil
+}
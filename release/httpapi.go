@@ -0,0 +1,702 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigatewayv2"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/pkg/errors"
+)
+
+type HTTPAPIConfig struct {
+	// Name is the name of the API, the app's name by default. An existing
+	// API with the name is reused.
+	Name string `hcl:"name,optional"`
+
+	// Routes are the route keys sent to the function, such as
+	// "GET /orders/{id}", or "$default" for every request.
+	Routes []string `hcl:"routes,optional"`
+
+	// Stage is the stage serving the API, "$default" by default, which is
+	// served at the root of the API's URL.
+	Stage string `hcl:"stage,optional"`
+
+	Cors *CorsConfig `hcl:"cors,block"`
+
+	// Domain serves the API from a custom domain name.
+	Domain *DomainConfig `hcl:"domain,block"`
+}
+
+type CorsConfig struct {
+	AllowOrigins     []string `hcl:"allow_origins,optional"`
+	AllowMethods     []string `hcl:"allow_methods,optional"`
+	AllowHeaders     []string `hcl:"allow_headers,optional"`
+	ExposeHeaders    []string `hcl:"expose_headers,optional"`
+	AllowCredentials bool     `hcl:"allow_credentials,optional"`
+
+	// MaxAge is how long browsers cache the preflight response, such as
+	// "1h".
+	MaxAge string `hcl:"max_age,optional"`
+}
+
+type DomainConfig struct {
+	// Name is the domain name, such as api.example.com.
+	Name string `hcl:"name"`
+
+	// CertificateArn is an ACM certificate for the domain in the API's
+	// region.
+	CertificateArn string `hcl:"certificate_arn"`
+
+	// BasePath is the path the API is served under, the root by default.
+	BasePath string `hcl:"base_path,optional"`
+}
+
+const (
+	// The route and stage used when none are given.
+	DefaultRoute = "$default"
+	DefaultStage = "$default"
+
+	// The longest browsers may cache a preflight response for.
+	maxCorsAge = 24 * time.Hour
+)
+
+var (
+	routeKey  = regexp.MustCompile(`^(GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS|ANY) /\S*$`)
+	stageName = regexp.MustCompile(`^[A-Za-z0-9_\-]{1,128}$`)
+)
+
+func (c *HTTPAPIConfig) validate() error {
+	for _, r := range c.Routes {
+		if r != DefaultRoute && !routeKey.MatchString(r) {
+			return fmt.Errorf("http_api route %q must be $default or a method and path, such as \"GET /orders\"", r)
+		}
+	}
+
+	if c.Stage != "" && c.Stage != DefaultStage && !stageName.MatchString(c.Stage) {
+		return fmt.Errorf("http_api stage %q must be $default or letters, digits, '_' or '-'", c.Stage)
+	}
+
+	if c.Cors != nil {
+		if err := validateDuration("http_api cors max_age", c.Cors.MaxAge, 0, maxCorsAge); err != nil {
+			return err
+		}
+
+		if c.Cors.AllowCredentials {
+			for _, o := range c.Cors.AllowOrigins {
+				if o == "*" {
+					return fmt.Errorf("http_api cors allow_credentials can't be used with the origin \"*\"")
+				}
+			}
+		}
+	}
+
+	if d := c.Domain; d != nil {
+		if !strings.Contains(d.CertificateArn, ":acm:") {
+			return fmt.Errorf("http_api domain certificate_arn %q is not an ACM certificate ARN", d.CertificateArn)
+		}
+
+		if strings.HasPrefix(d.BasePath, "/") {
+			return fmt.Errorf("http_api domain base_path must not start with /")
+		}
+	}
+
+	return nil
+}
+
+// name returns the name of the API.
+func (c *HTTPAPIConfig) name(src *component.Source) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return src.App
+}
+
+// routes returns the route keys sent to the function.
+func (c *HTTPAPIConfig) routes() []string {
+	if len(c.Routes) == 0 {
+		return []string{DefaultRoute}
+	}
+	return c.Routes
+}
+
+// stage returns the name of the stage serving the API.
+func (c *HTTPAPIConfig) stage() string {
+	if c.Stage == "" {
+		return DefaultStage
+	}
+	return c.Stage
+}
+
+// cors returns the API's CORS configuration, or nil if it has none.
+func (c *HTTPAPIConfig) cors() *apigatewayv2.Cors {
+	if c.Cors == nil {
+		return nil
+	}
+
+	return &apigatewayv2.Cors{
+		AllowOrigins:     aws.StringSlice(c.Cors.AllowOrigins),
+		AllowMethods:     aws.StringSlice(c.Cors.AllowMethods),
+		AllowHeaders:     aws.StringSlice(c.Cors.AllowHeaders),
+		ExposeHeaders:    aws.StringSlice(c.Cors.ExposeHeaders),
+		AllowCredentials: aws.Bool(c.Cors.AllowCredentials),
+		MaxAge:           seconds(c.Cors.MaxAge),
+	}
+}
+
+// apiStatementId is the ID of the permission letting the API invoke the
+// function.
+func apiStatementId(apiId string) string {
+	return fmt.Sprintf("lambda-apigateway-%s", apiId)
+}
+
+// apiSourceArn is the ARN of every route of the API, in the account and
+// region of the function.
+func apiSourceArn(funcArn, apiId string) string {
+	return fmt.Sprintf("arn:%s:execute-api:%s:%s:%s/*/*", arnField(funcArn, 1), arnField(funcArn, 3), arnField(funcArn, 4), apiId)
+}
+
+// apiRelease is an HTTP API being released.
+type apiRelease struct {
+	*HTTPAPIConfig
+
+	api           *apigatewayv2.Api
+	managed       bool
+	integrationId string
+	endpoint      string
+
+	// What the integration invoked before the release, empty if the release
+	// created it, in which case createdRoutes are the routes sent to it.
+	previousArn   string
+	createdRoutes []string
+
+	domainName    string
+	mappingId     string
+	managedDomain bool
+
+	// The host name the custom domain's DNS record must point at.
+	domainTarget string
+}
+
+// url returns the URL the API is served from.
+func (a *apiRelease) url() string {
+	if a.Domain != nil {
+		url := "https://" + a.Domain.Name
+		if a.Domain.BasePath != "" {
+			url += "/" + a.Domain.BasePath
+		}
+		return url
+	}
+
+	url := aws.StringValue(a.api.ApiEndpoint)
+	if stage := a.stage(); stage != DefaultStage {
+		url += "/" + stage
+	}
+	return url
+}
+
+// releaseHTTPAPI creates or updates the API, its integration with arn, its
+// routes, stage and custom domain.
+func (rm *ReleaseManager) releaseHTTPAPI(
+	ctx context.Context,
+	lamSvc *lambda.Lambda,
+	apiSvc *apigatewayv2.ApiGatewayV2,
+	src *component.Source,
+	arn string,
+) (*apiRelease, error) {
+	a := &apiRelease{
+		HTTPAPIConfig: rm.config.HTTPAPI,
+		endpoint:      arn,
+	}
+
+	var err error
+	a.api, a.managed, err = a.ensureAPI(ctx, apiSvc, src)
+	if err != nil {
+		return nil, err
+	}
+
+	apiId := aws.StringValue(a.api.ApiId)
+
+	// The API must be allowed to invoke arn before it is integrated
+	_, err = lamSvc.AddPermissionWithContext(ctx, &lambda.AddPermissionInput{
+		StatementId:  aws.String(apiStatementId(apiId)),
+		FunctionName: aws.String(arn),
+		Action:       aws.String("lambda:InvokeFunction"),
+		Principal:    aws.String("apigateway.amazonaws.com"),
+		SourceArn:    aws.String(apiSourceArn(arn, apiId)),
+	})
+
+	if err != nil && !isCode(err, lambda.ErrCodeResourceConflictException) {
+		return nil, errors.Wrapf(err, "unable to let API %s invoke the function", apiId)
+	}
+
+	if err := a.ensureIntegration(ctx, apiSvc); err != nil {
+		return nil, err
+	}
+
+	if err := a.ensureRoutes(ctx, apiSvc); err != nil {
+		return nil, err
+	}
+
+	if err := a.ensureStage(ctx, apiSvc); err != nil {
+		return nil, err
+	}
+
+	if a.Domain != nil {
+		if err := a.ensureDomain(ctx, apiSvc, src); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// ensureStage creates the stage, or turns on automatic deployment of an
+// existing one so the integration and routes go live.
+func (a *apiRelease) ensureStage(ctx context.Context, apiSvc *apigatewayv2.ApiGatewayV2) error {
+	stage, err := apiSvc.GetStageWithContext(ctx, &apigatewayv2.GetStageInput{
+		ApiId:     a.api.ApiId,
+		StageName: aws.String(a.stage()),
+	})
+
+	switch {
+	case isCode(err, apigatewayv2.ErrCodeNotFoundException):
+		_, err = apiSvc.CreateStageWithContext(ctx, &apigatewayv2.CreateStageInput{
+			ApiId:      a.api.ApiId,
+			StageName:  aws.String(a.stage()),
+			AutoDeploy: aws.Bool(true),
+		})
+	case err == nil && !aws.BoolValue(stage.AutoDeploy):
+		// Turning on automatic deployment also deploys the current routes
+		_, err = apiSvc.UpdateStageWithContext(ctx, &apigatewayv2.UpdateStageInput{
+			ApiId:      a.api.ApiId,
+			StageName:  aws.String(a.stage()),
+			AutoDeploy: aws.Bool(true),
+		})
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "unable to update stage %s of API %s", a.stage(), aws.StringValue(a.api.ApiId))
+	}
+
+	return nil
+}
+
+// ensureAPI finds the API by name, creating it if it doesn't exist, and
+// reports whether the plugin manages it.
+func (a *apiRelease) ensureAPI(
+	ctx context.Context,
+	apiSvc *apigatewayv2.ApiGatewayV2,
+	src *component.Source,
+) (*apigatewayv2.Api, bool, error) {
+	name := a.name(src)
+
+	api, err := findAPI(ctx, apiSvc, name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if api == nil {
+		out, err := apiSvc.CreateApiWithContext(ctx, &apigatewayv2.CreateApiInput{
+			Name:              aws.String(name),
+			ProtocolType:      aws.String(apigatewayv2.ProtocolTypeHttp),
+			CorsConfiguration: a.cors(),
+			Tags: map[string]*string{
+				"waypoint.app": aws.String(src.App),
+			},
+		})
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "unable to create API %s", name)
+		}
+
+		return &apigatewayv2.Api{
+			ApiId:       out.ApiId,
+			ApiEndpoint: out.ApiEndpoint,
+			Name:        out.Name,
+		}, true, nil
+	}
+
+	managed := aws.StringValue(api.Tags["waypoint.app"]) == src.App
+
+	// The CORS configuration of an API the plugin doesn't manage is only
+	// changed when the release sets one
+	switch {
+	case a.Cors != nil:
+		_, err = apiSvc.UpdateApiWithContext(ctx, &apigatewayv2.UpdateApiInput{
+			ApiId:             api.ApiId,
+			CorsConfiguration: a.cors(),
+		})
+	case managed && api.CorsConfiguration != nil:
+		_, err = apiSvc.DeleteCorsConfigurationWithContext(ctx, &apigatewayv2.DeleteCorsConfigurationInput{
+			ApiId: api.ApiId,
+		})
+	}
+
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "unable to update the CORS configuration of API %s", name)
+	}
+
+	return api, managed, nil
+}
+
+// findAPI returns the HTTP API with the given name, or nil if there is none.
+func findAPI(ctx context.Context, apiSvc *apigatewayv2.ApiGatewayV2, name string) (*apigatewayv2.Api, error) {
+	input := &apigatewayv2.GetApisInput{}
+
+	for {
+		out, err := apiSvc.GetApisWithContext(ctx, input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to list APIs")
+		}
+
+		for _, api := range out.Items {
+			if aws.StringValue(api.Name) == name && aws.StringValue(api.ProtocolType) == apigatewayv2.ProtocolTypeHttp {
+				return api, nil
+			}
+		}
+
+		if out.NextToken == nil {
+			return nil, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// findIntegration returns the API's proxy integration with any version or
+// alias of the function, or nil if there is none.
+func findIntegration(
+	ctx context.Context,
+	apiSvc *apigatewayv2.ApiGatewayV2,
+	apiId string,
+	funcArn string,
+) (*apigatewayv2.Integration, error) {
+	input := &apigatewayv2.GetIntegrationsInput{ApiId: aws.String(apiId)}
+
+	for {
+		out, err := apiSvc.GetIntegrationsWithContext(ctx, input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to list integrations of API %s", apiId)
+		}
+
+		for _, i := range out.Items {
+			if aws.StringValue(i.IntegrationType) == apigatewayv2.IntegrationTypeAwsProxy &&
+				unqualified(aws.StringValue(i.IntegrationUri)) == unqualified(funcArn) {
+				return i, nil
+			}
+		}
+
+		if out.NextToken == nil {
+			return nil, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// ensureIntegration points the function's integration at the released
+// version or alias, creating it if it doesn't exist.
+func (a *apiRelease) ensureIntegration(ctx context.Context, apiSvc *apigatewayv2.ApiGatewayV2) error {
+	apiId := aws.StringValue(a.api.ApiId)
+
+	cur, err := findIntegration(ctx, apiSvc, apiId, a.endpoint)
+	if err != nil {
+		return err
+	}
+
+	if cur == nil {
+		out, err := apiSvc.CreateIntegrationWithContext(ctx, &apigatewayv2.CreateIntegrationInput{
+			ApiId:                a.api.ApiId,
+			IntegrationType:      aws.String(apigatewayv2.IntegrationTypeAwsProxy),
+			IntegrationUri:       aws.String(a.endpoint),
+			PayloadFormatVersion: aws.String("2.0"),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "unable to create integration of API %s", apiId)
+		}
+
+		a.integrationId = aws.StringValue(out.IntegrationId)
+		return nil
+	}
+
+	a.integrationId = aws.StringValue(cur.IntegrationId)
+	a.previousArn = aws.StringValue(cur.IntegrationUri)
+
+	if a.previousArn == a.endpoint {
+		return nil
+	}
+
+	_, err = apiSvc.UpdateIntegrationWithContext(ctx, &apigatewayv2.UpdateIntegrationInput{
+		ApiId:          a.api.ApiId,
+		IntegrationId:  aws.String(a.integrationId),
+		IntegrationUri: aws.String(a.endpoint),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to update integration of API %s", apiId)
+	}
+
+	return nil
+}
+
+// ensureRoutes sends the configured routes to the integration and deletes
+// routes sent to it that are no longer configured.
+func (a *apiRelease) ensureRoutes(ctx context.Context, apiSvc *apigatewayv2.ApiGatewayV2) error {
+	target := "integrations/" + a.integrationId
+
+	routes, err := listRoutes(ctx, apiSvc, aws.StringValue(a.api.ApiId))
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]*apigatewayv2.Route{}
+	for _, r := range routes {
+		existing[aws.StringValue(r.RouteKey)] = r
+	}
+
+	wanted := map[string]bool{}
+	for _, key := range a.routes() {
+		wanted[key] = true
+
+		r, ok := existing[key]
+		switch {
+		case !ok:
+			out, err := apiSvc.CreateRouteWithContext(ctx, &apigatewayv2.CreateRouteInput{
+				ApiId:    a.api.ApiId,
+				RouteKey: aws.String(key),
+				Target:   aws.String(target),
+			})
+			if err != nil {
+				return errors.Wrapf(err, "unable to create route %s", key)
+			}
+
+			a.createdRoutes = append(a.createdRoutes, aws.StringValue(out.RouteId))
+		case aws.StringValue(r.Target) != target:
+			_, err = apiSvc.UpdateRouteWithContext(ctx, &apigatewayv2.UpdateRouteInput{
+				ApiId:   a.api.ApiId,
+				RouteId: r.RouteId,
+				Target:  aws.String(target),
+			})
+			if err != nil {
+				return errors.Wrapf(err, "unable to update route %s", key)
+			}
+		}
+	}
+
+	for key, r := range existing {
+		if wanted[key] || aws.StringValue(r.Target) != target {
+			continue
+		}
+
+		_, err = apiSvc.DeleteRouteWithContext(ctx, &apigatewayv2.DeleteRouteInput{
+			ApiId:   a.api.ApiId,
+			RouteId: r.RouteId,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "unable to delete route %s", key)
+		}
+	}
+
+	return nil
+}
+
+// listRoutes returns every route of the API.
+func listRoutes(ctx context.Context, apiSvc *apigatewayv2.ApiGatewayV2, apiId string) ([]*apigatewayv2.Route, error) {
+	var routes []*apigatewayv2.Route
+
+	input := &apigatewayv2.GetRoutesInput{ApiId: aws.String(apiId)}
+	for {
+		out, err := apiSvc.GetRoutesWithContext(ctx, input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to list routes of API %s", apiId)
+		}
+
+		routes = append(routes, out.Items...)
+
+		if out.NextToken == nil {
+			return routes, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+// ensureDomain creates the custom domain if it doesn't exist and maps the
+// API's stage to it.
+func (a *apiRelease) ensureDomain(
+	ctx context.Context,
+	apiSvc *apigatewayv2.ApiGatewayV2,
+	src *component.Source,
+) error {
+	a.domainName = a.Domain.Name
+
+	var configs []*apigatewayv2.DomainNameConfiguration
+
+	domain, err := apiSvc.GetDomainNameWithContext(ctx, &apigatewayv2.GetDomainNameInput{
+		DomainName: aws.String(a.domainName),
+	})
+
+	switch {
+	case err == nil:
+		configs = domain.DomainNameConfigurations
+		a.managedDomain = aws.StringValue(domain.Tags["waypoint.app"]) == src.App
+	case isCode(err, apigatewayv2.ErrCodeNotFoundException):
+		out, err := apiSvc.CreateDomainNameWithContext(ctx, &apigatewayv2.CreateDomainNameInput{
+			DomainName: aws.String(a.domainName),
+			DomainNameConfigurations: []*apigatewayv2.DomainNameConfiguration{{
+				CertificateArn: aws.String(a.Domain.CertificateArn),
+				EndpointType:   aws.String(apigatewayv2.EndpointTypeRegional),
+				SecurityPolicy: aws.String(apigatewayv2.SecurityPolicyTls12),
+			}},
+			Tags: map[string]*string{
+				"waypoint.app": aws.String(src.App),
+			},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "unable to create domain name %s", a.domainName)
+		}
+
+		configs = out.DomainNameConfigurations
+		a.managedDomain = true
+	default:
+		return errors.Wrapf(err, "unable to read domain name %s", a.domainName)
+	}
+
+	if len(configs) > 0 {
+		a.domainTarget = aws.StringValue(configs[0].ApiGatewayDomainName)
+	}
+
+	input := &apigatewayv2.GetApiMappingsInput{DomainName: aws.String(a.domainName)}
+	for {
+		out, err := apiSvc.GetApiMappingsWithContext(ctx, input)
+		if err != nil {
+			return errors.Wrapf(err, "unable to list API mappings of %s", a.domainName)
+		}
+
+		for _, m := range out.Items {
+			if aws.StringValue(m.ApiId) == aws.StringValue(a.api.ApiId) &&
+				aws.StringValue(m.Stage) == a.stage() &&
+				aws.StringValue(m.ApiMappingKey) == a.Domain.BasePath {
+				a.mappingId = aws.StringValue(m.ApiMappingId)
+				return nil
+			}
+		}
+
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	out, err := apiSvc.CreateApiMappingWithContext(ctx, &apigatewayv2.CreateApiMappingInput{
+		DomainName:    aws.String(a.domainName),
+		ApiId:         a.api.ApiId,
+		Stage:         aws.String(a.stage()),
+		ApiMappingKey: aws.String(a.Domain.BasePath),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to map API to %s", a.domainName)
+	}
+
+	a.mappingId = aws.StringValue(out.ApiMappingId)
+
+	return nil
+}
+
+// restore points the integration back at what it invoked before the release,
+// or deletes the routes the release created for it.
+func (a *apiRelease) restore(ctx context.Context, apiSvc *apigatewayv2.ApiGatewayV2) error {
+	if a.previousArn != "" {
+		if a.previousArn == a.endpoint {
+			return nil
+		}
+
+		_, err := apiSvc.UpdateIntegrationWithContext(ctx, &apigatewayv2.UpdateIntegrationInput{
+			ApiId:          a.api.ApiId,
+			IntegrationId:  aws.String(a.integrationId),
+			IntegrationUri: aws.String(a.previousArn),
+		})
+		return err
+	}
+
+	for _, id := range a.createdRoutes {
+		_, err := apiSvc.DeleteRouteWithContext(ctx, &apigatewayv2.DeleteRouteInput{
+			ApiId:   a.api.ApiId,
+			RouteId: aws.String(id),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteHTTPAPI removes what the release added to the API: the whole API and
+// domain if the plugin created them, otherwise the function's routes,
+// integration and domain mapping.
+func deleteHTTPAPI(ctx context.Context, apiSvc *apigatewayv2.ApiGatewayV2, api *HttpApi) error {
+	ignore := func(err error) error {
+		if isCode(errors.Cause(err), apigatewayv2.ErrCodeNotFoundException) {
+			return nil
+		}
+		return err
+	}
+
+	if api.DomainName != "" {
+		var err error
+		if api.ManagedDomain {
+			_, err = apiSvc.DeleteDomainNameWithContext(ctx, &apigatewayv2.DeleteDomainNameInput{
+				DomainName: aws.String(api.DomainName),
+			})
+		} else {
+			_, err = apiSvc.DeleteApiMappingWithContext(ctx, &apigatewayv2.DeleteApiMappingInput{
+				DomainName:   aws.String(api.DomainName),
+				ApiMappingId: aws.String(api.ApiMappingId),
+			})
+		}
+		if err := ignore(err); err != nil {
+			return errors.Wrapf(err, "unable to remove the API from %s", api.DomainName)
+		}
+	}
+
+	if api.Managed {
+		_, err := apiSvc.DeleteApiWithContext(ctx, &apigatewayv2.DeleteApiInput{
+			ApiId: aws.String(api.ApiId),
+		})
+		if err := ignore(err); err != nil {
+			return errors.Wrapf(err, "unable to delete API %s", api.ApiId)
+		}
+		return nil
+	}
+
+	routes, err := listRoutes(ctx, apiSvc, api.ApiId)
+	if err := ignore(err); err != nil {
+		return err
+	}
+
+	for _, r := range routes {
+		if aws.StringValue(r.Target) != "integrations/"+api.IntegrationId {
+			continue
+		}
+
+		_, err = apiSvc.DeleteRouteWithContext(ctx, &apigatewayv2.DeleteRouteInput{
+			ApiId:   aws.String(api.ApiId),
+			RouteId: r.RouteId,
+		})
+		if err := ignore(err); err != nil {
+			return errors.Wrapf(err, "unable to delete route %s", aws.StringValue(r.RouteKey))
+		}
+	}
+
+	_, err = apiSvc.DeleteIntegrationWithContext(ctx, &apigatewayv2.DeleteIntegrationInput{
+		ApiId:         aws.String(api.ApiId),
+		IntegrationId: aws.String(api.IntegrationId),
+	})
+	if err := ignore(err); err != nil {
+		return errors.Wrapf(err, "unable to delete integration of API %s", api.ApiId)
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type InputTransformerConfig struct {
+	// InputPaths maps names to JSON paths into the event, such as
+	// { status = "$.detail.status" }.
+	InputPaths map[string]string `hcl:"input_paths,optional"`
+
+	// InputTemplate is the input passed to the function, with <name>
+	// replaced by the value at the named path.
+	InputTemplate string `hcl:"input_template"`
+}
+
+// Placeholders EventBridge fills in without an input path.
+var predefinedInputs = map[string]bool{
+	"aws.events.rule-arn":             true,
+	"aws.events.rule-name":            true,
+	"aws.events.event.ingestion-time": true,
+	"aws.events.event":                true,
+	"aws.events.event.json":           true,
+}
+
+var (
+	inputName   = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+	placeholder = regexp.MustCompile(`<([A-Za-z0-9_.\-]+)>`)
+)
+
+func (c *InputTransformerConfig) validate() error {
+	if len(c.InputPaths) > 100 {
+		return fmt.Errorf("input_transformer allows at most 100 input_paths")
+	}
+
+	for name, path := range c.InputPaths {
+		if !inputName.MatchString(name) || strings.HasPrefix(name, "aws.") {
+			return fmt.Errorf("input_transformer name %q must be letters, digits, '_' or '-'", name)
+		}
+
+		if err := validateInputPath(path); err != nil {
+			return fmt.Errorf("input_transformer %s: %s", name, err)
+		}
+	}
+
+	for _, m := range placeholder.FindAllStringSubmatch(c.InputTemplate, -1) {
+		if _, ok := c.InputPaths[m[1]]; !ok && !predefinedInputs[m[1]] {
+			return fmt.Errorf("input_template refers to <%s>, which is not in input_paths", m[1])
+		}
+	}
+
+	return nil
+}
+
+func validateInputPath(path string) error {
+	if !strings.HasPrefix(path, "$") {
+		return fmt.Errorf("path %q must start with $", path)
+	}
+
+	if len(path) > 256 {
+		return fmt.Errorf("path %q is longer than 256 characters", path)
+	}
+
+	return nil
+}
+
+// render fills in the template from a sample event, returning an error if a
+// path is missing from the event or the result isn't valid JSON when the
+// template is.
+func (c *InputTransformerConfig) render(event []byte) (string, error) {
+	var doc interface{}
+	if err := json.Unmarshal(event, &doc); err != nil {
+		return "", fmt.Errorf("event is not valid JSON: %s", err)
+	}
+
+	values := map[string]interface{}{
+		"aws.events.rule-arn":             "arn:aws:events:region:account:rule/name",
+		"aws.events.rule-name":            "name",
+		"aws.events.event.ingestion-time": "1970-01-01T00:00:00Z",
+		"aws.events.event":                doc,
+		"aws.events.event.json":           doc,
+	}
+
+	for name, path := range c.InputPaths {
+		v, err := lookupPath(doc, path)
+		if err != nil {
+			return "", err
+		}
+		values[name] = v
+	}
+
+	tmpl := c.InputTemplate
+
+	var out strings.Builder
+	last := 0
+	for _, m := range placeholder.FindAllStringSubmatchIndex(tmpl, -1) {
+		start, end := m[0], m[1]
+		out.WriteString(tmpl[last:start])
+		last = end
+
+		v := values[tmpl[m[2]:m[3]]]
+
+		// Strings go in without quotes, escaped if the template quotes
+		// them, and anything else as JSON
+		if s, ok := v.(string); ok {
+			quoted := start > 0 && tmpl[start-1] == '"' && end < len(tmpl) && tmpl[end] == '"'
+			if quoted {
+				data, _ := json.Marshal(s)
+				s = string(data[1 : len(data)-1])
+			}
+			out.WriteString(s)
+			continue
+		}
+
+		data, _ := json.Marshal(v)
+		out.Write(data)
+	}
+	out.WriteString(tmpl[last:])
+
+	result := out.String()
+
+	trimmed := strings.TrimSpace(tmpl)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		if !json.Valid([]byte(result)) {
+			return "", fmt.Errorf("input_template renders invalid JSON: %s", result)
+		}
+	}
+
+	return result, nil
+}
+
+// lookupPath resolves an input path made of "$", ".key" and "[index]"
+// segments against a decoded event.
+func lookupPath(doc interface{}, path string) (interface{}, error) {
+	rest := strings.TrimPrefix(path, "$")
+	cur := doc
+
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+
+			key := rest[:end]
+			rest = rest[end:]
+
+			obj, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: %q is not an object", path, key)
+			}
+
+			cur, ok = obj[key]
+			if !ok {
+				return nil, fmt.Errorf("%s: no key %q in event", path, key)
+			}
+
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("%s: unterminated index", path)
+			}
+
+			idx, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid index %q", path, rest[1:end])
+			}
+
+			rest = rest[end+1:]
+
+			arr, ok := cur.([]interface{})
+			if !ok || idx < 0 || idx >= len(arr) {
+				return nil, fmt.Errorf("%s: index %d out of range", path, idx)
+			}
+
+			cur = arr[idx]
+
+		default:
+			return nil, fmt.Errorf("%s: unexpected %q", path, rest[0])
+		}
+	}
+
+	return cur, nil
+}
@@ -0,0 +1,149 @@
+package release
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/pkg/errors"
+)
+
+// mappingRelease is an event source mapping being released.
+type mappingRelease struct {
+	// What the mapping reads from, such as "SQS queue".
+	kind      string
+	sourceArn string
+
+	uuid    string
+	enabled bool
+
+	// What the mapping invoked before the release, empty if it was created
+	// by the release.
+	previousArn string
+}
+
+// mappingSource is an event source the function consumes through an event
+// source mapping.
+type mappingSource interface {
+	// kind describes the source for messages, such as "SQS queue".
+	kind() string
+
+	// mappingInputs returns the requests creating or updating the mapping
+	// from the source to arn.
+	mappingInputs(arn string) (*lambda.CreateEventSourceMappingInput, *lambda.UpdateEventSourceMappingInput)
+}
+
+// mappingSources returns every source the release maps to the function.
+func (rm *ReleaseManager) mappingSources() []mappingSource {
+	var sources []mappingSource
+
+	if rm.config.SQS != nil {
+		sources = append(sources, rm.config.SQS)
+	}
+
+	for _, s := range rm.config.Streams {
+		sources = append(sources, s)
+	}
+
+	return sources
+}
+
+// unqualified strips the version or alias from a function ARN.
+func unqualified(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) > 7 {
+		parts = parts[:7]
+	}
+	return strings.Join(parts, ":")
+}
+
+// findMapping returns the mapping from sourceArn to any version or alias of
+// the function, or nil if there is none.
+func findMapping(
+	ctx context.Context,
+	lamSvc *lambda.Lambda,
+	sourceArn string,
+	funcArn string,
+) (*lambda.EventSourceMappingConfiguration, error) {
+	var found *lambda.EventSourceMappingConfiguration
+
+	err := lamSvc.ListEventSourceMappingsPagesWithContext(ctx, &lambda.ListEventSourceMappingsInput{
+		EventSourceArn: aws.String(sourceArn),
+	}, func(out *lambda.ListEventSourceMappingsOutput, last bool) bool {
+		for _, m := range out.EventSourceMappings {
+			if unqualified(aws.StringValue(m.FunctionArn)) == unqualified(funcArn) {
+				found = m
+				return false
+			}
+		}
+		return true
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list event source mappings of %s", sourceArn)
+	}
+
+	return found, nil
+}
+
+// ensureMapping updates the function's existing mapping from the event source
+// to the settings and qualifier in update, or creates one from create.
+func ensureMapping(
+	ctx context.Context,
+	lamSvc *lambda.Lambda,
+	kind string,
+	create *lambda.CreateEventSourceMappingInput,
+	update *lambda.UpdateEventSourceMappingInput,
+) (*mappingRelease, error) {
+	m := &mappingRelease{
+		kind:      kind,
+		sourceArn: aws.StringValue(create.EventSourceArn),
+		enabled:   aws.BoolValue(create.Enabled),
+	}
+
+	cur, err := findMapping(ctx, lamSvc, m.sourceArn, aws.StringValue(create.FunctionName))
+	if err != nil {
+		return nil, err
+	}
+
+	if cur == nil {
+		out, err := lamSvc.CreateEventSourceMappingWithContext(ctx, create)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to connect %s %s", kind, m.sourceArn)
+		}
+
+		m.uuid = aws.StringValue(out.UUID)
+		return m, nil
+	}
+
+	m.uuid = aws.StringValue(cur.UUID)
+	m.previousArn = aws.StringValue(cur.FunctionArn)
+
+	update.UUID = cur.UUID
+	update.FunctionName = create.FunctionName
+
+	_, err = lamSvc.UpdateEventSourceMappingWithContext(ctx, update)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to update the mapping of %s %s", kind, m.sourceArn)
+	}
+
+	return m, nil
+}
+
+// restore points the mapping back at what it invoked before the release, or
+// deletes it if the release created it.
+func (m *mappingRelease) restore(ctx context.Context, lamSvc *lambda.Lambda) error {
+	if m.previousArn != "" {
+		_, err := lamSvc.UpdateEventSourceMappingWithContext(ctx, &lambda.UpdateEventSourceMappingInput{
+			UUID:         aws.String(m.uuid),
+			FunctionName: aws.String(m.previousArn),
+		})
+		return err
+	}
+
+	_, err := lamSvc.DeleteEventSourceMappingWithContext(ctx, &lambda.DeleteEventSourceMappingInput{
+		UUID: aws.String(m.uuid),
+	})
+	return err
+}
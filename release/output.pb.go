@@ -21,12 +21,38 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
 type Release struct {
-	Url                  string   `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
-	EventSource          string   `protobuf:"bytes,3,opt,name=event_source,json=eventSource,proto3" json:"event_source,omitempty"`
-	FunctionArn          string   `protobuf:"bytes,4,opt,name=function_arn,json=functionArn,proto3" json:"function_arn,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Url         string `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	EventSource string `protobuf:"bytes,3,opt,name=event_source,json=eventSource,proto3" json:"event_source,omitempty"`
+	FunctionArn string `protobuf:"bytes,4,opt,name=function_arn,json=functionArn,proto3" json:"function_arn,omitempty"`
+	// The name of the alias the EventBridge target invokes, if any.
+	Alias string `protobuf:"bytes,5,opt,name=alias,proto3" json:"alias,omitempty"`
+	// The traffic weights (in percent) shifted onto the new version.
+	Weights []int64 `protobuf:"varint,6,rep,packed,name=weights,proto3" json:"weights,omitempty"`
+	// The version the alias served before this release.
+	PreviousVersion string `protobuf:"bytes,7,opt,name=previous_version,json=previousVersion,proto3" json:"previous_version,omitempty"`
+	// The CloudWatch alarms created by the plugin for this release.
+	ManagedAlarms []string `protobuf:"bytes,8,rep,name=managed_alarms,json=managedAlarms,proto3" json:"managed_alarms,omitempty"`
+	// The CodeDeploy deployment that shifted traffic for this release.
+	DeploymentId string `protobuf:"bytes,9,opt,name=deployment_id,json=deploymentId,proto3" json:"deployment_id,omitempty"`
+	// The schedule expression the rule runs on, if any.
+	Schedule string `protobuf:"bytes,10,opt,name=schedule,proto3" json:"schedule,omitempty"`
+	// Every EventBridge rule the release manages.
+	Rules []*Rule `protobuf:"bytes,11,rep,name=rules,proto3" json:"rules,omitempty"`
+	// The URL of the dead-letter queue created by the plugin, if any.
+	ManagedDeadLetterQueue string `protobuf:"bytes,12,opt,name=managed_dead_letter_queue,json=managedDeadLetterQueue,proto3" json:"managed_dead_letter_queue,omitempty"`
+	// Every event source mapping the release manages.
+	EventSourceMappings []*EventSourceMapping `protobuf:"bytes,13,rep,name=event_source_mappings,json=eventSourceMappings,proto3" json:"event_source_mappings,omitempty"`
+	// Every S3 bucket notification entry the release manages.
+	BucketNotifications []*BucketNotification `protobuf:"bytes,14,rep,name=bucket_notifications,json=bucketNotifications,proto3" json:"bucket_notifications,omitempty"`
+	// Every SNS subscription the release manages.
+	Subscriptions []*Subscription `protobuf:"bytes,15,rep,name=subscriptions,proto3" json:"subscriptions,omitempty"`
+	// The HTTP API serving the function, if any.
+	HttpApi *HttpApi `protobuf:"bytes,16,opt,name=http_api,json=httpApi,proto3" json:"http_api,omitempty"`
+	// The load balancer target group serving the function, if any.
+	Alb                  *AlbTarget `protobuf:"bytes,17,opt,name=alb,proto3" json:"alb,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
 }
 
 func (m *Release) Reset()         { *m = Release{} }
@@ -75,8 +101,517 @@ func (m *Release) GetFunctionArn() string {
 	return ""
 }
 
+func (m *Release) GetAlias() string {
+	if m != nil {
+		return m.Alias
+	}
+	return ""
+}
+
+func (m *Release) GetWeights() []int64 {
+	if m != nil {
+		return m.Weights
+	}
+	return nil
+}
+
+func (m *Release) GetPreviousVersion() string {
+	if m != nil {
+		return m.PreviousVersion
+	}
+	return ""
+}
+
+func (m *Release) GetManagedAlarms() []string {
+	if m != nil {
+		return m.ManagedAlarms
+	}
+	return nil
+}
+
+func (m *Release) GetDeploymentId() string {
+	if m != nil {
+		return m.DeploymentId
+	}
+	return ""
+}
+
+func (m *Release) GetSchedule() string {
+	if m != nil {
+		return m.Schedule
+	}
+	return ""
+}
+
+func (m *Release) GetRules() []*Rule {
+	if m != nil {
+		return m.Rules
+	}
+	return nil
+}
+
+func (m *Release) GetManagedDeadLetterQueue() string {
+	if m != nil {
+		return m.ManagedDeadLetterQueue
+	}
+	return ""
+}
+
+func (m *Release) GetEventSourceMappings() []*EventSourceMapping {
+	if m != nil {
+		return m.EventSourceMappings
+	}
+	return nil
+}
+
+func (m *Release) GetBucketNotifications() []*BucketNotification {
+	if m != nil {
+		return m.BucketNotifications
+	}
+	return nil
+}
+
+func (m *Release) GetSubscriptions() []*Subscription {
+	if m != nil {
+		return m.Subscriptions
+	}
+	return nil
+}
+
+func (m *Release) GetHttpApi() *HttpApi {
+	if m != nil {
+		return m.HttpApi
+	}
+	return nil
+}
+
+func (m *Release) GetAlb() *AlbTarget {
+	if m != nil {
+		return m.Alb
+	}
+	return nil
+}
+
+type Rule struct {
+	Name     string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	EventBus string `protobuf:"bytes,2,opt,name=event_bus,json=eventBus,proto3" json:"event_bus,omitempty"`
+	RuleArn  string `protobuf:"bytes,3,opt,name=rule_arn,json=ruleArn,proto3" json:"rule_arn,omitempty"`
+	// The ARN the rule's target invokes.
+	TargetArn            string   `protobuf:"bytes,4,opt,name=target_arn,json=targetArn,proto3" json:"target_arn,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Rule) Reset()         { *m = Rule{} }
+func (m *Rule) String() string { return proto.CompactTextString(m) }
+func (*Rule) ProtoMessage()    {}
+func (*Rule) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b9b80318c8639236, []int{1}
+}
+
+func (m *Rule) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Rule.Unmarshal(m, b)
+}
+func (m *Rule) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Rule.Marshal(b, m, deterministic)
+}
+func (m *Rule) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Rule.Merge(m, src)
+}
+func (m *Rule) XXX_Size() int {
+	return xxx_messageInfo_Rule.Size(m)
+}
+func (m *Rule) XXX_DiscardUnknown() {
+	xxx_messageInfo_Rule.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Rule proto.InternalMessageInfo
+
+func (m *Rule) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Rule) GetEventBus() string {
+	if m != nil {
+		return m.EventBus
+	}
+	return ""
+}
+
+func (m *Rule) GetRuleArn() string {
+	if m != nil {
+		return m.RuleArn
+	}
+	return ""
+}
+
+func (m *Rule) GetTargetArn() string {
+	if m != nil {
+		return m.TargetArn
+	}
+	return ""
+}
+
+type EventSourceMapping struct {
+	Uuid           string `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	EventSourceArn string `protobuf:"bytes,2,opt,name=event_source_arn,json=eventSourceArn,proto3" json:"event_source_arn,omitempty"`
+	// The ARN the mapping invokes.
+	FunctionArn          string   `protobuf:"bytes,3,opt,name=function_arn,json=functionArn,proto3" json:"function_arn,omitempty"`
+	Enabled              bool     `protobuf:"varint,4,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EventSourceMapping) Reset()         { *m = EventSourceMapping{} }
+func (m *EventSourceMapping) String() string { return proto.CompactTextString(m) }
+func (*EventSourceMapping) ProtoMessage()    {}
+func (*EventSourceMapping) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b9b80318c8639236, []int{2}
+}
+
+func (m *EventSourceMapping) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EventSourceMapping.Unmarshal(m, b)
+}
+func (m *EventSourceMapping) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EventSourceMapping.Marshal(b, m, deterministic)
+}
+func (m *EventSourceMapping) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EventSourceMapping.Merge(m, src)
+}
+func (m *EventSourceMapping) XXX_Size() int {
+	return xxx_messageInfo_EventSourceMapping.Size(m)
+}
+func (m *EventSourceMapping) XXX_DiscardUnknown() {
+	xxx_messageInfo_EventSourceMapping.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EventSourceMapping proto.InternalMessageInfo
+
+func (m *EventSourceMapping) GetUuid() string {
+	if m != nil {
+		return m.Uuid
+	}
+	return ""
+}
+
+func (m *EventSourceMapping) GetEventSourceArn() string {
+	if m != nil {
+		return m.EventSourceArn
+	}
+	return ""
+}
+
+func (m *EventSourceMapping) GetFunctionArn() string {
+	if m != nil {
+		return m.FunctionArn
+	}
+	return ""
+}
+
+func (m *EventSourceMapping) GetEnabled() bool {
+	if m != nil {
+		return m.Enabled
+	}
+	return false
+}
+
+type BucketNotification struct {
+	Bucket  string `protobuf:"bytes,1,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	Account string `protobuf:"bytes,2,opt,name=account,proto3" json:"account,omitempty"`
+	// The ID of the app's entry in the bucket's notification configuration.
+	Id string `protobuf:"bytes,3,opt,name=id,proto3" json:"id,omitempty"`
+	// The ARN the entry invokes.
+	FunctionArn          string   `protobuf:"bytes,4,opt,name=function_arn,json=functionArn,proto3" json:"function_arn,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BucketNotification) Reset()         { *m = BucketNotification{} }
+func (m *BucketNotification) String() string { return proto.CompactTextString(m) }
+func (*BucketNotification) ProtoMessage()    {}
+func (*BucketNotification) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b9b80318c8639236, []int{3}
+}
+
+func (m *BucketNotification) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BucketNotification.Unmarshal(m, b)
+}
+func (m *BucketNotification) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BucketNotification.Marshal(b, m, deterministic)
+}
+func (m *BucketNotification) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BucketNotification.Merge(m, src)
+}
+func (m *BucketNotification) XXX_Size() int {
+	return xxx_messageInfo_BucketNotification.Size(m)
+}
+func (m *BucketNotification) XXX_DiscardUnknown() {
+	xxx_messageInfo_BucketNotification.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BucketNotification proto.InternalMessageInfo
+
+func (m *BucketNotification) GetBucket() string {
+	if m != nil {
+		return m.Bucket
+	}
+	return ""
+}
+
+func (m *BucketNotification) GetAccount() string {
+	if m != nil {
+		return m.Account
+	}
+	return ""
+}
+
+func (m *BucketNotification) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *BucketNotification) GetFunctionArn() string {
+	if m != nil {
+		return m.FunctionArn
+	}
+	return ""
+}
+
+type Subscription struct {
+	TopicArn        string `protobuf:"bytes,1,opt,name=topic_arn,json=topicArn,proto3" json:"topic_arn,omitempty"`
+	SubscriptionArn string `protobuf:"bytes,2,opt,name=subscription_arn,json=subscriptionArn,proto3" json:"subscription_arn,omitempty"`
+	// The ARN the subscription delivers to.
+	FunctionArn          string   `protobuf:"bytes,3,opt,name=function_arn,json=functionArn,proto3" json:"function_arn,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Subscription) Reset()         { *m = Subscription{} }
+func (m *Subscription) String() string { return proto.CompactTextString(m) }
+func (*Subscription) ProtoMessage()    {}
+func (*Subscription) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b9b80318c8639236, []int{4}
+}
+
+func (m *Subscription) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Subscription.Unmarshal(m, b)
+}
+func (m *Subscription) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Subscription.Marshal(b, m, deterministic)
+}
+func (m *Subscription) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Subscription.Merge(m, src)
+}
+func (m *Subscription) XXX_Size() int {
+	return xxx_messageInfo_Subscription.Size(m)
+}
+func (m *Subscription) XXX_DiscardUnknown() {
+	xxx_messageInfo_Subscription.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Subscription proto.InternalMessageInfo
+
+func (m *Subscription) GetTopicArn() string {
+	if m != nil {
+		return m.TopicArn
+	}
+	return ""
+}
+
+func (m *Subscription) GetSubscriptionArn() string {
+	if m != nil {
+		return m.SubscriptionArn
+	}
+	return ""
+}
+
+func (m *Subscription) GetFunctionArn() string {
+	if m != nil {
+		return m.FunctionArn
+	}
+	return ""
+}
+
+type HttpApi struct {
+	ApiId         string `protobuf:"bytes,1,opt,name=api_id,json=apiId,proto3" json:"api_id,omitempty"`
+	IntegrationId string `protobuf:"bytes,2,opt,name=integration_id,json=integrationId,proto3" json:"integration_id,omitempty"`
+	// The ARN the integration invokes.
+	FunctionArn string `protobuf:"bytes,3,opt,name=function_arn,json=functionArn,proto3" json:"function_arn,omitempty"`
+	// Whether the plugin created the API, and deletes it on destroy.
+	Managed              bool     `protobuf:"varint,4,opt,name=managed,proto3" json:"managed,omitempty"`
+	DomainName           string   `protobuf:"bytes,5,opt,name=domain_name,json=domainName,proto3" json:"domain_name,omitempty"`
+	ApiMappingId         string   `protobuf:"bytes,6,opt,name=api_mapping_id,json=apiMappingId,proto3" json:"api_mapping_id,omitempty"`
+	ManagedDomain        bool     `protobuf:"varint,7,opt,name=managed_domain,json=managedDomain,proto3" json:"managed_domain,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HttpApi) Reset()         { *m = HttpApi{} }
+func (m *HttpApi) String() string { return proto.CompactTextString(m) }
+func (*HttpApi) ProtoMessage()    {}
+func (*HttpApi) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b9b80318c8639236, []int{5}
+}
+
+func (m *HttpApi) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HttpApi.Unmarshal(m, b)
+}
+func (m *HttpApi) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HttpApi.Marshal(b, m, deterministic)
+}
+func (m *HttpApi) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HttpApi.Merge(m, src)
+}
+func (m *HttpApi) XXX_Size() int {
+	return xxx_messageInfo_HttpApi.Size(m)
+}
+func (m *HttpApi) XXX_DiscardUnknown() {
+	xxx_messageInfo_HttpApi.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HttpApi proto.InternalMessageInfo
+
+func (m *HttpApi) GetApiId() string {
+	if m != nil {
+		return m.ApiId
+	}
+	return ""
+}
+
+func (m *HttpApi) GetIntegrationId() string {
+	if m != nil {
+		return m.IntegrationId
+	}
+	return ""
+}
+
+func (m *HttpApi) GetFunctionArn() string {
+	if m != nil {
+		return m.FunctionArn
+	}
+	return ""
+}
+
+func (m *HttpApi) GetManaged() bool {
+	if m != nil {
+		return m.Managed
+	}
+	return false
+}
+
+func (m *HttpApi) GetDomainName() string {
+	if m != nil {
+		return m.DomainName
+	}
+	return ""
+}
+
+func (m *HttpApi) GetApiMappingId() string {
+	if m != nil {
+		return m.ApiMappingId
+	}
+	return ""
+}
+
+func (m *HttpApi) GetManagedDomain() bool {
+	if m != nil {
+		return m.ManagedDomain
+	}
+	return false
+}
+
+type AlbTarget struct {
+	TargetGroupArn string `protobuf:"bytes,1,opt,name=target_group_arn,json=targetGroupArn,proto3" json:"target_group_arn,omitempty"`
+	// The ARN registered with the target group.
+	FunctionArn string `protobuf:"bytes,2,opt,name=function_arn,json=functionArn,proto3" json:"function_arn,omitempty"`
+	RuleArn     string `protobuf:"bytes,3,opt,name=rule_arn,json=ruleArn,proto3" json:"rule_arn,omitempty"`
+	// Whether the plugin created the rule and target group, and deletes them
+	// on destroy.
+	ManagedRule          bool     `protobuf:"varint,4,opt,name=managed_rule,json=managedRule,proto3" json:"managed_rule,omitempty"`
+	ManagedTargetGroup   bool     `protobuf:"varint,5,opt,name=managed_target_group,json=managedTargetGroup,proto3" json:"managed_target_group,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AlbTarget) Reset()         { *m = AlbTarget{} }
+func (m *AlbTarget) String() string { return proto.CompactTextString(m) }
+func (*AlbTarget) ProtoMessage()    {}
+func (*AlbTarget) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b9b80318c8639236, []int{6}
+}
+
+func (m *AlbTarget) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AlbTarget.Unmarshal(m, b)
+}
+func (m *AlbTarget) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AlbTarget.Marshal(b, m, deterministic)
+}
+func (m *AlbTarget) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AlbTarget.Merge(m, src)
+}
+func (m *AlbTarget) XXX_Size() int {
+	return xxx_messageInfo_AlbTarget.Size(m)
+}
+func (m *AlbTarget) XXX_DiscardUnknown() {
+	xxx_messageInfo_AlbTarget.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AlbTarget proto.InternalMessageInfo
+
+func (m *AlbTarget) GetTargetGroupArn() string {
+	if m != nil {
+		return m.TargetGroupArn
+	}
+	return ""
+}
+
+func (m *AlbTarget) GetFunctionArn() string {
+	if m != nil {
+		return m.FunctionArn
+	}
+	return ""
+}
+
+func (m *AlbTarget) GetRuleArn() string {
+	if m != nil {
+		return m.RuleArn
+	}
+	return ""
+}
+
+func (m *AlbTarget) GetManagedRule() bool {
+	if m != nil {
+		return m.ManagedRule
+	}
+	return false
+}
+
+func (m *AlbTarget) GetManagedTargetGroup() bool {
+	if m != nil {
+		return m.ManagedTargetGroup
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterType((*Release)(nil), "release.Release")
+	proto.RegisterType((*Rule)(nil), "release.Rule")
+	proto.RegisterType((*EventSourceMapping)(nil), "release.EventSourceMapping")
+	proto.RegisterType((*BucketNotification)(nil), "release.BucketNotification")
+	proto.RegisterType((*Subscription)(nil), "release.Subscription")
+	proto.RegisterType((*HttpApi)(nil), "release.HttpApi")
+	proto.RegisterType((*AlbTarget)(nil), "release.AlbTarget")
 }
 
 func init() {
@@ -84,16 +619,56 @@ func init() {
 }
 
 var fileDescriptor_b9b80318c8639236 = []byte{
-	// 163 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0x29, 0x4a, 0xcd, 0x49,
-	0x4d, 0x2c, 0x4e, 0xd5, 0xcf, 0x2f, 0x2d, 0x29, 0x28, 0x2d, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9,
-	0x17, 0x62, 0x87, 0x8a, 0x2a, 0x25, 0x72, 0xb1, 0x07, 0x41, 0x98, 0x42, 0x02, 0x5c, 0xcc, 0xa5,
-	0x45, 0x39, 0x12, 0x8c, 0x0a, 0x8c, 0x1a, 0x9c, 0x41, 0x20, 0xa6, 0x90, 0x22, 0x17, 0x4f, 0x6a,
-	0x59, 0x6a, 0x5e, 0x49, 0x7c, 0x71, 0x7e, 0x69, 0x51, 0x72, 0xaa, 0x04, 0x33, 0x58, 0x8a, 0x1b,
-	0x2c, 0x16, 0x0c, 0x16, 0x02, 0x29, 0x49, 0x2b, 0xcd, 0x4b, 0x2e, 0xc9, 0xcc, 0xcf, 0x8b, 0x4f,
-	0x2c, 0xca, 0x93, 0x60, 0x81, 0x28, 0x81, 0x89, 0x39, 0x16, 0xe5, 0x39, 0x69, 0x44, 0xa9, 0xa5,
-	0x67, 0x96, 0x64, 0x94, 0x26, 0xe9, 0x25, 0xe7, 0xe7, 0xea, 0x17, 0x64, 0xe4, 0x27, 0x25, 0xe6,
-	0x19, 0x18, 0xea, 0xe7, 0x24, 0xe6, 0x26, 0xa5, 0x24, 0xea, 0xa6, 0x56, 0x94, 0xe8, 0x43, 0x1d,
-	0x93, 0xc4, 0x06, 0x76, 0x9c, 0x31, 0x20, 0x00, 0x00, 0xff, 0xff, 0x33, 0xec, 0x68, 0xef, 0xb4,
-	0x00, 0x00, 0x00,
+	// 803 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x55, 0xdb, 0x8e, 0x1b, 0x45,
+	0x10, 0x95, 0xd7, 0x6b, 0x7b, 0x5c, 0xbe, 0xac, 0xe9, 0xec, 0x46, 0x1d, 0x22, 0x84, 0xe3, 0x04,
+	0xe4, 0x08, 0xb1, 0x0e, 0xe1, 0x09, 0xf1, 0xe4, 0x55, 0x10, 0xac, 0x04, 0x8b, 0x98, 0x44, 0x3c,
+	0xf0, 0x32, 0xea, 0x99, 0xee, 0xd8, 0x2d, 0x66, 0x7a, 0x3a, 0x7d, 0x59, 0xd8, 0xaf, 0xe0, 0x6b,
+	0xf8, 0x07, 0xbe, 0x87, 0x2f, 0x40, 0x7d, 0x99, 0xd9, 0xc9, 0x1a, 0x45, 0xfb, 0xd6, 0x75, 0xaa,
+	0xa6, 0xea, 0xf4, 0xa9, 0xaa, 0x1e, 0x38, 0x55, 0xac, 0x64, 0x44, 0xb3, 0x4d, 0x6d, 0x8d, 0xb4,
+	0xe6, 0x5c, 0xaa, 0xda, 0xd4, 0x68, 0x14, 0xd1, 0xd5, 0xdf, 0x03, 0x18, 0xa5, 0xe1, 0x8c, 0x16,
+	0xd0, 0xb7, 0xaa, 0xc4, 0xbd, 0x65, 0x6f, 0x3d, 0x4e, 0xdd, 0x11, 0x3d, 0x81, 0x29, 0xbb, 0x66,
+	0xc2, 0x64, 0xba, 0xb6, 0xaa, 0x60, 0xb8, 0xef, 0x5d, 0x13, 0x8f, 0xbd, 0xf6, 0x90, 0x0b, 0x79,
+	0x6b, 0x45, 0x61, 0x78, 0x2d, 0x32, 0xa2, 0x04, 0x3e, 0x0e, 0x21, 0x0d, 0xb6, 0x55, 0x02, 0x9d,
+	0xc2, 0x80, 0x94, 0x9c, 0x68, 0x3c, 0xf0, 0xbe, 0x60, 0x20, 0x0c, 0xa3, 0x3f, 0x18, 0xdf, 0xed,
+	0x8d, 0xc6, 0xc3, 0x65, 0x7f, 0xdd, 0x4f, 0x1b, 0x13, 0x3d, 0x87, 0x85, 0x54, 0xec, 0x9a, 0xd7,
+	0x56, 0x67, 0xd7, 0x4c, 0x69, 0x5e, 0x0b, 0x3c, 0xf2, 0x9f, 0x9e, 0x34, 0xf8, 0xaf, 0x01, 0x46,
+	0x9f, 0xc1, 0xbc, 0x22, 0x82, 0xec, 0x18, 0xcd, 0x48, 0x49, 0x54, 0xa5, 0x71, 0xb2, 0xec, 0xaf,
+	0xc7, 0xe9, 0x2c, 0xa2, 0x5b, 0x0f, 0xa2, 0xa7, 0x30, 0xa3, 0x4c, 0x96, 0xf5, 0x4d, 0xe5, 0x2e,
+	0xc3, 0x29, 0x1e, 0xfb, 0x74, 0xd3, 0x5b, 0xf0, 0x92, 0xa2, 0x8f, 0x21, 0xd1, 0xc5, 0x9e, 0x51,
+	0x5b, 0x32, 0x0c, 0xde, 0xdf, 0xda, 0xe8, 0x29, 0x0c, 0x94, 0x2d, 0x99, 0xc6, 0x93, 0x65, 0x7f,
+	0x3d, 0x79, 0x39, 0x3b, 0x8f, 0xfa, 0x9d, 0xa7, 0xb6, 0x64, 0x69, 0xf0, 0xa1, 0x6f, 0xe0, 0x51,
+	0x43, 0x86, 0x32, 0x42, 0xb3, 0x92, 0x19, 0xc3, 0x54, 0xf6, 0xce, 0x32, 0xcb, 0xf0, 0xd4, 0x67,
+	0x7c, 0x18, 0x03, 0x5e, 0x31, 0x42, 0x7f, 0xf4, 0xee, 0x5f, 0x9c, 0x17, 0xfd, 0x0c, 0x67, 0x5d,
+	0xa1, 0xb3, 0x8a, 0x48, 0xc9, 0xc5, 0x4e, 0xe3, 0x99, 0xaf, 0xf7, 0xb8, 0xad, 0xf7, 0xdd, 0xad,
+	0xf4, 0x3f, 0x85, 0x98, 0xf4, 0x01, 0x3b, 0xc0, 0x34, 0xba, 0x82, 0xd3, 0xdc, 0x16, 0xbf, 0x33,
+	0x93, 0x89, 0xda, 0xf0, 0xb7, 0xbc, 0x20, 0xae, 0x1b, 0x1a, 0xcf, 0xef, 0xe4, 0xbb, 0xf0, 0x41,
+	0x57, 0x9d, 0x98, 0xf4, 0x41, 0x7e, 0x80, 0x69, 0xf4, 0x2d, 0xcc, 0xb4, 0xcd, 0x75, 0xa1, 0xb8,
+	0x0c, 0x89, 0x4e, 0x7c, 0xa2, 0xb3, 0x36, 0xd1, 0xeb, 0x8e, 0x37, 0x7d, 0x3f, 0x16, 0x7d, 0x01,
+	0xc9, 0xde, 0x18, 0x99, 0x11, 0xc9, 0xf1, 0x62, 0xd9, 0x5b, 0x4f, 0x5e, 0x2e, 0xda, 0xef, 0x7e,
+	0x30, 0x46, 0x6e, 0x25, 0x4f, 0x47, 0xfb, 0x70, 0x40, 0xcf, 0xa0, 0x4f, 0xca, 0x1c, 0x7f, 0xe4,
+	0xe3, 0x50, 0x1b, 0xb7, 0x2d, 0xf3, 0x37, 0x44, 0xed, 0x98, 0x49, 0x9d, 0x7b, 0xf5, 0x0e, 0x8e,
+	0x9d, 0xf4, 0x08, 0xc1, 0xb1, 0x20, 0x15, 0x8b, 0x43, 0xeb, 0xcf, 0xe8, 0x31, 0x8c, 0x83, 0x98,
+	0xb9, 0xd5, 0xf8, 0x28, 0x74, 0xd2, 0x03, 0x17, 0x56, 0xa3, 0x47, 0x90, 0xb8, 0x6e, 0xf9, 0x59,
+	0x0d, 0xe3, 0x3c, 0x72, 0xb6, 0x9b, 0xd3, 0x4f, 0x00, 0x8c, 0x2f, 0xd1, 0x19, 0xe4, 0x71, 0x40,
+	0xb6, 0x4a, 0xac, 0xfe, 0xea, 0x01, 0x3a, 0x94, 0xdf, 0x31, 0xb0, 0x96, 0xd3, 0x86, 0x81, 0x3b,
+	0xa3, 0x35, 0x2c, 0xde, 0x6b, 0xa7, 0xcb, 0x17, 0x88, 0xcc, 0x3b, 0xcd, 0x72, 0x35, 0xef, 0xae,
+	0x4f, 0xff, 0x70, 0x7d, 0x30, 0x8c, 0x98, 0x20, 0x79, 0xc9, 0xa8, 0xe7, 0x94, 0xa4, 0x8d, 0xb9,
+	0xba, 0x01, 0x74, 0xd8, 0x3f, 0xf4, 0x10, 0x86, 0xa1, 0x83, 0x91, 0x52, 0xb4, 0x5c, 0x1e, 0x52,
+	0x14, 0xb5, 0x15, 0x26, 0x72, 0x69, 0x4c, 0x34, 0x87, 0x23, 0x4e, 0x63, 0xe9, 0x23, 0x4e, 0xef,
+	0xb1, 0xd3, 0xab, 0x1b, 0x98, 0x76, 0x3b, 0xee, 0x34, 0x37, 0xb5, 0xe4, 0x85, 0x8f, 0x0f, 0x75,
+	0x13, 0x0f, 0xb8, 0x1b, 0x3c, 0x87, 0x45, 0x77, 0x20, 0x3a, 0x72, 0x9c, 0x74, 0xf1, 0xfb, 0xe9,
+	0xb1, 0xfa, 0xb7, 0x07, 0xa3, 0x38, 0x35, 0xe8, 0x0c, 0x86, 0x44, 0xf2, 0xac, 0x95, 0x7f, 0x40,
+	0x24, 0xbf, 0xa4, 0xee, 0x59, 0xe0, 0xc2, 0xb0, 0x9d, 0xf2, 0x8a, 0x38, 0x77, 0x28, 0x37, 0xeb,
+	0xa0, 0x97, 0xf4, 0x9e, 0xe2, 0xc7, 0x95, 0x6d, 0xc4, 0x8f, 0x26, 0xfa, 0x14, 0x26, 0xb4, 0xae,
+	0x08, 0x17, 0x99, 0x1f, 0xc0, 0xf0, 0xb6, 0x41, 0x80, 0xae, 0xdc, 0x18, 0x3e, 0x83, 0xb9, 0xe3,
+	0x16, 0x57, 0xd9, 0x91, 0x18, 0x86, 0x57, 0x87, 0x48, 0x1e, 0x87, 0x27, 0x50, 0x6d, 0x1f, 0x0d,
+	0xff, 0xad, 0x7f, 0xea, 0x92, 0xf6, 0x05, 0x7b, 0xe5, 0xc1, 0xd5, 0x3f, 0x3d, 0x18, 0xb7, 0x2b,
+	0xe0, 0xe6, 0x2b, 0x4e, 0xea, 0x4e, 0xd5, 0x56, 0x76, 0x44, 0x9f, 0x07, 0xfc, 0x7b, 0x07, 0xff,
+	0x9f, 0x9e, 0x47, 0x87, 0x57, 0xfc, 0xc0, 0x46, 0x3c, 0x81, 0x69, 0x43, 0xce, 0x41, 0x51, 0x82,
+	0x49, 0xc4, 0xfc, 0x02, 0xbe, 0x80, 0xd3, 0x26, 0xa4, 0x4b, 0xc9, 0xeb, 0x91, 0xa4, 0x28, 0xfa,
+	0xde, 0xdc, 0xb2, 0xba, 0x58, 0xff, 0xf6, 0xf9, 0x8e, 0x9b, 0xbd, 0xcd, 0xcf, 0x8b, 0xba, 0xda,
+	0xc8, 0x7d, 0x9d, 0x13, 0xf1, 0xe2, 0xab, 0x4d, 0x49, 0xaa, 0x9c, 0x92, 0x2f, 0xd9, 0x9f, 0x66,
+	0x13, 0x77, 0x3e, 0x1f, 0xfa, 0x9f, 0xd5, 0xd7, 0xff, 0x0d, 0x00, 0x8b, 0xa4, 0xdb, 0x0a, 0xc4,
+	0x06, 0x00, 0x00,
 }
@@ -0,0 +1,296 @@
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/phoban01/lambda-ext/eventpattern"
+	"github.com/pkg/errors"
+)
+
+type EventPatternConfig struct {
+	Source     []string `hcl:"source,optional"`
+	DetailType []string `hcl:"detail_type,optional"`
+	Account    []string `hcl:"account,optional"`
+	Resources  []string `hcl:"resources,optional"`
+
+	// Detail matches fields of the event detail.
+	Detail []*DetailMatcher `hcl:"detail,block"`
+
+	// File is a path, relative to the app, to a raw JSON event pattern used
+	// instead of the fields above.
+	File string `hcl:"file,optional"`
+}
+
+type DetailMatcher struct {
+	// Field is the dotted path of the field within detail, such as
+	// "order.status".
+	Field string `hcl:"field,label"`
+
+	// Equals matches any of the given values exactly.
+	Equals []string `hcl:"equals,optional"`
+
+	Prefix      string   `hcl:"prefix,optional"`
+	AnythingBut []string `hcl:"anything_but,optional"`
+
+	// Numeric is one or two comparisons, such as [">", "0", "<=", "100"].
+	Numeric []string `hcl:"numeric,optional"`
+
+	// Exists matches on whether the field is present at all.
+	Exists *bool `hcl:"exists,optional"`
+}
+
+// The comparisons allowed in a numeric matcher.
+var numericOperators = map[string]bool{
+	"<": true, "<=": true, "=": true, ">": true, ">=": true,
+}
+
+func (c *EventPatternConfig) validate() error {
+	if c.File != "" {
+		if len(c.Source) > 0 || len(c.DetailType) > 0 || len(c.Account) > 0 ||
+			len(c.Resources) > 0 || len(c.Detail) > 0 {
+			return fmt.Errorf("event_pattern file can't be combined with other event_pattern fields")
+		}
+		return nil
+	}
+
+	pattern, err := c.build()
+	if err != nil {
+		return err
+	}
+
+	if len(pattern) == 0 {
+		return fmt.Errorf("event_pattern must match on at least one field")
+	}
+
+	return nil
+}
+
+// build turns the structured fields into an event pattern.
+func (c *EventPatternConfig) build() (map[string]interface{}, error) {
+	pattern := map[string]interface{}{}
+
+	for key, values := range map[string][]string{
+		"source":      c.Source,
+		"detail-type": c.DetailType,
+		"account":     c.Account,
+		"resources":   c.Resources,
+	} {
+		if len(values) > 0 {
+			pattern[key] = values
+		}
+	}
+
+	if len(c.Detail) == 0 {
+		return pattern, nil
+	}
+
+	detail := map[string]interface{}{}
+
+	for _, d := range c.Detail {
+		matchers, err := d.matchers()
+		if err != nil {
+			return nil, err
+		}
+
+		path := strings.Split(d.Field, ".")
+
+		obj := detail
+		for i, key := range path {
+			if key == "" {
+				return nil, fmt.Errorf("invalid detail field %q", d.Field)
+			}
+
+			if i == len(path)-1 {
+				if _, ok := obj[key]; ok {
+					return nil, fmt.Errorf("detail field %q is matched more than once", d.Field)
+				}
+				obj[key] = matchers
+				break
+			}
+
+			next, ok := obj[key]
+			if !ok {
+				next = map[string]interface{}{}
+				obj[key] = next
+			}
+
+			obj, ok = next.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("detail field %q is nested under a matched field", d.Field)
+			}
+		}
+	}
+
+	pattern["detail"] = detail
+
+	return pattern, nil
+}
+
+// matchers returns the list of values and content filters for the field.
+func (d *DetailMatcher) matchers() ([]interface{}, error) {
+	var matchers []interface{}
+
+	for _, v := range d.Equals {
+		matchers = append(matchers, v)
+	}
+
+	if d.Prefix != "" {
+		matchers = append(matchers, map[string]interface{}{"prefix": d.Prefix})
+	}
+
+	if len(d.AnythingBut) > 0 {
+		matchers = append(matchers, map[string]interface{}{"anything-but": d.AnythingBut})
+	}
+
+	if len(d.Numeric) > 0 {
+		if len(d.Numeric)%2 != 0 || len(d.Numeric) > 4 {
+			return nil, fmt.Errorf("detail %q numeric must be one or two operator and value pairs", d.Field)
+		}
+
+		var numeric []interface{}
+		for i := 0; i < len(d.Numeric); i += 2 {
+			op := d.Numeric[i]
+			if !numericOperators[op] {
+				return nil, fmt.Errorf("detail %q numeric has unknown operator %q", d.Field, op)
+			}
+
+			n, err := strconv.ParseFloat(d.Numeric[i+1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("detail %q numeric value %q is not a number", d.Field, d.Numeric[i+1])
+			}
+
+			numeric = append(numeric, op, n)
+		}
+
+		matchers = append(matchers, map[string]interface{}{"numeric": numeric})
+	}
+
+	if d.Exists != nil {
+		matchers = append(matchers, map[string]interface{}{"exists": *d.Exists})
+	}
+
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("detail %q has nothing to match on", d.Field)
+	}
+
+	return matchers, nil
+}
+
+// eventPattern returns the JSON pattern of the rule, read from event_pattern
+// or event_source and validated.
+func (r *RuleConfig) eventPattern(src *component.Source) (string, error) {
+	c := r.EventPattern
+
+	if c == nil {
+		pattern := map[string]interface{}{
+			"source": []string{aws.StringValue(r.EventSource)},
+		}
+
+		data, err := json.Marshal(pattern)
+		return string(data), err
+	}
+
+	if c.File != "" {
+		path := c.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(src.Path, path)
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", errors.Wrapf(err, "unable to read event pattern")
+		}
+
+		if _, err := eventpattern.Parse(data); err != nil {
+			return "", errors.Wrapf(err, "invalid event pattern in %s", c.File)
+		}
+
+		return string(data), nil
+	}
+
+	pattern, err := c.build()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	// The structured form is valid by construction, but check anyway so
+	// both forms are held to the same rules
+	if _, err := eventpattern.Parse(data); err != nil {
+		return "", errors.Wrapf(err, "invalid event_pattern")
+	}
+
+	return string(data), nil
+}
+
+// testEvents matches the pattern against the sample events in test_events,
+// and renders the input_transformer template for those that match, writing
+// a line per event to the step and failing if any expectation is wrong or
+// any template doesn't render.
+func (r *RuleConfig) testEvents(step terminal.Step, src *component.Source, pattern string) error {
+	p, err := eventpattern.Parse([]byte(pattern))
+	if err != nil {
+		return err
+	}
+
+	dir := r.TestEvents
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(src.Path, dir)
+	}
+
+	results, err := eventpattern.Verify(p, dir)
+	if err != nil {
+		return errors.Wrapf(err, "unable to read sample events")
+	}
+
+	failed := 0
+	for _, res := range results {
+		fmt.Fprintln(step.TermOutput(), res)
+		if !res.Passed() {
+			failed++
+			continue
+		}
+
+		if r.InputTransformer != nil && res.Matched {
+			input, err := r.InputTransformer.render(res.Event)
+			if err != nil {
+				fmt.Fprintf(step.TermOutput(), "%s: %s\n", res.File, err)
+				failed++
+				continue
+			}
+
+			fmt.Fprintf(step.TermOutput(), "%s: input %s\n", res.File, input)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sample events didn't match or transform as expected", failed, len(results))
+	}
+
+	step.Update("Event pattern matched all %d sample events as expected", len(results))
+
+	return nil
+}
+
+// jsonEqual reports whether a and b are equivalent JSON documents.
+func jsonEqual(a, b string) bool {
+	var av, bv interface{}
+	if json.Unmarshal([]byte(a), &av) != nil || json.Unmarshal([]byte(b), &bv) != nil {
+		return a == b
+	}
+
+	return reflect.DeepEqual(av, bv)
+}
@@ -0,0 +1,110 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/pkg/errors"
+)
+
+// hasStatement reports whether the resource policy of the function or
+// qualified function arn holds a statement with the given ID.
+func hasStatement(ctx context.Context, lamSvc *lambda.Lambda, arn, sid string) (bool, error) {
+	statements, err := policyStatements(ctx, lamSvc, arn)
+	if err != nil {
+		return false, err
+	}
+
+	for _, s := range statements {
+		if s.Sid == sid {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// statement is the part of a resource policy statement the plugin reads.
+type statement struct {
+	Sid       string
+	Condition struct {
+		ArnLike map[string]string
+	}
+}
+
+// sourceArn returns the ARN the statement lets invoke the function, if any.
+func (s *statement) sourceArn() string {
+	return s.Condition.ArnLike["AWS:SourceArn"]
+}
+
+// policyStatements returns the statements of the resource policy of the
+// function or qualified function arn.
+func policyStatements(ctx context.Context, lamSvc *lambda.Lambda, arn string) ([]*statement, error) {
+	out, err := lamSvc.GetPolicyWithContext(ctx, &lambda.GetPolicyInput{
+		FunctionName: aws.String(arn),
+	})
+
+	if err != nil {
+		// Functions without any permissions have no policy at all
+		if isCode(err, lambda.ErrCodeResourceNotFoundException) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "unable to read the policy of %s", arn)
+	}
+
+	var policy struct {
+		Statement []*statement
+	}
+
+	if err := json.Unmarshal([]byte(aws.StringValue(out.Policy)), &policy); err != nil {
+		return nil, fmt.Errorf("unable to parse the policy of %s: %s", arn, err)
+	}
+
+	return policy.Statement, nil
+}
+
+// revokeSource removes every statement letting the source, such as a rule or
+// a topic, invoke arn.
+func revokeSource(ctx context.Context, lamSvc *lambda.Lambda, arn, sourceArn string) error {
+	statements, err := policyStatements(ctx, lamSvc, arn)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range statements {
+		if s.sourceArn() != sourceArn {
+			continue
+		}
+
+		if err := removeStatement(ctx, lamSvc, arn, s.Sid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeStatement removes the statement with the given ID from the policy of
+// arn, if it is still there.
+func removeStatement(ctx context.Context, lamSvc *lambda.Lambda, arn, sid string) error {
+	_, err := lamSvc.RemovePermissionWithContext(ctx, &lambda.RemovePermissionInput{
+		FunctionName: aws.String(arn),
+		StatementId:  aws.String(sid),
+	})
+
+	if err != nil && !isCode(err, lambda.ErrCodeResourceNotFoundException) {
+		return errors.Wrapf(err, "unable to remove permission %s from %s", sid, arn)
+	}
+
+	return nil
+}
+
+// isCode reports whether err is an AWS error with the given code.
+func isCode(err error, code string) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == code
+}
@@ -0,0 +1,295 @@
+package release
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigatewayv2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/phoban01/lambda-ext/platform"
+	"github.com/pkg/errors"
+)
+
+// planRow is one setting of the release and whether it would change.
+type planRow struct {
+	setting string
+	current string
+	desired string
+	changed bool
+}
+
+// plan reads the rules, their targets, the alias, the function's permissions,
+// and any event source mappings, bucket notifications, topic subscriptions and
+// HTTP API, and shows how a release would change them without making any
+// changes.
+func (rm *ReleaseManager) plan(
+	ctx context.Context,
+	sg terminal.StepGroup,
+	ui terminal.UI,
+	sess *session.Session,
+	src *component.Source,
+	deploy *platform.Deployment,
+	rules []*ruleRelease,
+) error {
+	step := sg.Add("Reading EventBridge rules")
+	defer step.Abort()
+
+	lamSvc := lambda.New(sess)
+	evSvc := eventbridge.New(sess)
+
+	var rows []planRow
+	row := func(setting, current, desired string, changed bool) {
+		if current == "" {
+			current = "-"
+		}
+		rows = append(rows, planRow{setting: setting, current: current, desired: desired, changed: changed})
+	}
+
+	targetArn := deploy.VerArn
+
+	if rm.config.Alias != nil {
+		name := rm.config.Alias.Name
+
+		var current string
+		alias, err := lamSvc.GetAliasWithContext(ctx, &lambda.GetAliasInput{
+			FunctionName: aws.String(deploy.FuncArn),
+			Name:         aws.String(name),
+		})
+
+		switch {
+		case err == nil:
+			current = "version " + aws.StringValue(alias.FunctionVersion)
+			targetArn = aws.StringValue(alias.AliasArn)
+		case isCode(err, lambda.ErrCodeResourceNotFoundException):
+			targetArn = deploy.FuncArn + ":" + name
+		default:
+			return errors.Wrapf(err, "unable to read alias %s", name)
+		}
+
+		desired := "version " + deploy.Version
+		row("alias "+name, current, desired, current != desired)
+	}
+
+	for _, r := range rules {
+		prefix := "rule " + r.Name + ": "
+
+		var curPattern, curSchedule, state string
+		rule, err := evSvc.DescribeRuleWithContext(ctx, &eventbridge.DescribeRuleInput{
+			Name:         aws.String(r.Name),
+			EventBusName: aws.String(r.EventBus),
+		})
+
+		switch {
+		case err == nil:
+			curPattern = aws.StringValue(rule.EventPattern)
+			curSchedule = aws.StringValue(rule.ScheduleExpression)
+			state = aws.StringValue(rule.State)
+		case isCode(err, eventbridge.ErrCodeResourceNotFoundException):
+		default:
+			return errors.Wrapf(err, "unable to read EventBridge rule %s", r.Name)
+		}
+
+		if r.Schedule != "" {
+			row(prefix+"schedule", curSchedule, r.Schedule, curSchedule != r.Schedule)
+		} else {
+			row(prefix+"event_pattern", curPattern, r.pattern, !jsonEqual(curPattern, r.pattern))
+		}
+		row(prefix+"state", state, eventbridge.RuleStateEnabled, state != eventbridge.RuleStateEnabled)
+
+		currentTarget, err := r.currentTarget(ctx, evSvc, src)
+		if err != nil {
+			return err
+		}
+
+		row(prefix+"target", currentTarget, targetArn, currentTarget != targetArn)
+
+		sid := ruleStatementId(ruleArn(deploy.FuncArn, r.EventBus, r.Name))
+		granted, err := hasStatement(ctx, lamSvc, targetArn, sid)
+		if err != nil {
+			return err
+		}
+
+		var permission string
+		if granted {
+			permission = sid
+		}
+
+		row(prefix+"permission", permission, sid, !granted)
+	}
+
+	for _, s := range rm.mappingSources() {
+		create, _ := s.mappingInputs(targetArn)
+		sourceArn := aws.StringValue(create.EventSourceArn)
+		prefix := s.kind() + " " + sourceArn + ": "
+
+		cur, err := findMapping(ctx, lamSvc, sourceArn, deploy.FuncArn)
+		if err != nil {
+			return err
+		}
+
+		var currentTarget, state string
+		if cur != nil {
+			currentTarget = aws.StringValue(cur.FunctionArn)
+			state = aws.StringValue(cur.State)
+		}
+
+		desired := "Enabled"
+		if !aws.BoolValue(create.Enabled) {
+			desired = "Disabled"
+		}
+
+		row(prefix+"function", currentTarget, targetArn, currentTarget != targetArn)
+		row(prefix+"state", state, desired, state != desired)
+	}
+
+	s3Svc := s3.New(sess)
+	for _, c := range rm.config.S3 {
+		prefix := "s3 " + c.Bucket + ": "
+
+		account := c.Account
+		if account == "" {
+			account = arnField(deploy.FuncArn, 4)
+		}
+
+		entry, err := findEntry(ctx, s3Svc, c.Bucket, account, notificationId(src))
+		if err != nil {
+			return err
+		}
+
+		var currentTarget string
+		if entry != nil {
+			currentTarget = aws.StringValue(entry.LambdaFunctionArn)
+		}
+
+		row(prefix+"function", currentTarget, targetArn, currentTarget != targetArn)
+
+		sid := c.statementId()
+		granted, err := hasStatement(ctx, lamSvc, targetArn, sid)
+		if err != nil {
+			return err
+		}
+
+		var permission string
+		if granted {
+			permission = sid
+		}
+
+		row(prefix+"permission", permission, sid, !granted)
+	}
+
+	snsSvc := sns.New(sess)
+	for _, c := range rm.config.SNS {
+		prefix := "sns " + c.TopicArn + ": "
+
+		subs, err := findSubscriptions(ctx, snsSvc, c.TopicArn, deploy.FuncArn)
+		if err != nil {
+			return err
+		}
+
+		var endpoints []string
+		for _, sub := range subs {
+			endpoints = append(endpoints, aws.StringValue(sub.Endpoint))
+		}
+
+		current := strings.Join(endpoints, ", ")
+		row(prefix+"endpoint", current, targetArn, current != targetArn)
+
+		sid := c.statementId()
+		granted, err := hasStatement(ctx, lamSvc, targetArn, sid)
+		if err != nil {
+			return err
+		}
+
+		var permission string
+		if granted {
+			permission = sid
+		}
+
+		row(prefix+"permission", permission, sid, !granted)
+	}
+
+	if c := rm.config.HTTPAPI; c != nil {
+		name := c.name(src)
+		prefix := "http_api " + name + ": "
+
+		apiSvc := apigatewayv2.New(sess)
+
+		api, err := findAPI(ctx, apiSvc, name)
+		if err != nil {
+			return err
+		}
+
+		var current string
+		if api != nil {
+			integration, err := findIntegration(ctx, apiSvc, aws.StringValue(api.ApiId), deploy.FuncArn)
+			if err != nil {
+				return err
+			}
+
+			if integration != nil {
+				current = aws.StringValue(integration.IntegrationUri)
+			}
+		}
+
+		row(prefix+"integration", current, targetArn, current != targetArn)
+	}
+
+	if c := rm.config.ALB; c != nil {
+		prefix := "alb " + c.ListenerArn + ": "
+
+		elbSvc := elbv2.New(sess)
+
+		tgArn := c.TargetGroupArn
+		if tgArn == "" {
+			out, err := elbSvc.DescribeTargetGroupsWithContext(ctx, &elbv2.DescribeTargetGroupsInput{
+				Names: []*string{aws.String(targetGroupName(src))},
+			})
+			if err != nil && !isCode(err, elbv2.ErrCodeTargetGroupNotFoundException) {
+				return err
+			}
+
+			if err == nil && len(out.TargetGroups) > 0 {
+				tgArn = aws.StringValue(out.TargetGroups[0].TargetGroupArn)
+			}
+		}
+
+		var current string
+		if tgArn != "" {
+			target, err := registeredTarget(ctx, elbSvc, tgArn)
+			if err != nil {
+				return err
+			}
+			current = target
+		}
+
+		row(prefix+"target", current, targetArn, current != targetArn)
+	}
+
+	step.Done()
+	sg.Wait()
+
+	tbl := terminal.NewTable("Setting", "Current", "Desired", "Action")
+	for _, r := range rows {
+		action, color := "none", ""
+		if r.changed {
+			action, color = "update", terminal.Yellow
+		}
+
+		tbl.Rich(
+			[]string{r.setting, r.current, r.desired, action},
+			[]string{color, color, color, color},
+		)
+	}
+
+	ui.Table(tbl)
+
+	return platform.ErrPlanOnly
+}
@@ -3,24 +3,165 @@ package release
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigatewayv2"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/aws/aws-sdk-go/service/eventbridge"
 	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/waypoint-plugin-sdk/component"
 	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
 	"github.com/hashicorp/waypoint/builtin/aws/utils"
 	"github.com/phoban01/lambda-ext/platform"
+	"github.com/phoban01/lambda-ext/schedule"
+	"github.com/pkg/errors"
 )
 
 type ReleaseConfig struct {
 	Region      string  `hcl:"region,optional"`
 	EventBus    *string `hcl:"event_bus,optional"`
 	EventSource *string `hcl:"event_source,optional"`
-	Url         string  `hcl:"url,optional"`
+
+	// Url is shown as the release's URL, instead of the http_api's or
+	// alb's.
+	Url string `hcl:"url,optional"`
+
+	// EventPattern is the full pattern of the rule, used instead of
+	// event_source.
+	EventPattern *EventPatternConfig `hcl:"event_pattern,block"`
+
+	// TestEvents is a directory, relative to the app, of sample events the
+	// pattern is checked against before the rule is changed. Events under
+	// its match directory must match and those under no_match must not.
+	TestEvents string `hcl:"test_events,optional"`
+
+	// Schedule invokes the function on a cron(...) or rate(...) expression
+	// instead of in response to events.
+	Schedule string `hcl:"schedule,optional"`
+
+	// Input is constant JSON passed to the function instead of the event.
+	Input string `hcl:"input,optional"`
+
+	// InputPath selects the part of the event passed to the function,
+	// "$.detail" by default. "$" passes the whole event.
+	InputPath string `hcl:"input_path,optional"`
+
+	// InputTransformer reshapes the event before it is passed on.
+	InputTransformer *InputTransformerConfig `hcl:"input_transformer,block"`
+
+	// Rules are further EventBridge rules invoking the function, alongside
+	// the one the settings above describe.
+	Rules []*RuleConfig `hcl:"rule,block"`
+
+	// MaxRetryAttempts and MaxEventAge bound how hard EventBridge tries to
+	// deliver each event to the function, such as 3 and "1h".
+	MaxRetryAttempts *int64 `hcl:"max_retry_attempts,optional"`
+	MaxEventAge      string `hcl:"max_event_age,optional"`
+
+	// DeadLetterQueue receives the events EventBridge gives up on.
+	DeadLetterQueue *DeadLetterQueueConfig `hcl:"dead_letter_queue,block"`
+
+	// SQS has the function consume a queue, alongside or instead of any
+	// EventBridge rules.
+	SQS *SQSConfig `hcl:"sqs,block"`
+
+	// Streams are Kinesis or DynamoDB streams the function consumes.
+	Streams []*StreamConfig `hcl:"stream,block"`
+
+	// S3 subscribes the function to object events of buckets.
+	S3 []*S3Config `hcl:"s3,block"`
+
+	// SNS subscribes the function to topics.
+	SNS []*SNSConfig `hcl:"sns,block"`
+
+	// HTTPAPI serves the function through an API Gateway HTTP API.
+	HTTPAPI *HTTPAPIConfig `hcl:"http_api,block"`
+
+	// ALB registers the function with an Application Load Balancer target
+	// group.
+	ALB *ALBConfig `hcl:"alb,block"`
+
+	// Alias, when set, has the release manage a Lambda alias and shift
+	// traffic onto the new version gradually instead of all at once.
+	Alias *AliasConfig `hcl:"alias,block"`
+
+	// Rollback watches CloudWatch alarms while the release bakes and points
+	// the function back at the previous version if any of them fire.
+	Rollback *RollbackConfig `hcl:"rollback,block"`
+
+	// CodeDeploy hands traffic shifting on the alias over to a CodeDeploy
+	// deployment instead of the plugin's own weights.
+	CodeDeploy *CodeDeployConfig `hcl:"code_deploy,block"`
+
+	// PlanOnly shows how a release would change the rules, their targets,
+	// the alias and the other event sources, and fails it without making
+	// any changes.
+	PlanOnly bool `hcl:"plan_only,optional"`
+}
+
+type AliasConfig struct {
+	Name string `hcl:"name,optional"`
+
+	// Weights are the percentages of traffic routed to the new version at
+	// each step. The final step is always 100.
+	Weights []int64 `hcl:"weights,optional"`
+
+	// Interval is how long each step bakes before moving to the next one.
+	Interval string `hcl:"interval,optional"`
+}
+
+type RollbackConfig struct {
+	// Alarms are the names of existing CloudWatch alarms to watch.
+	Alarms []string `hcl:"alarms,optional"`
+
+	// CreateAlarms has the plugin create Errors and Throttles alarms scoped
+	// to the new version.
+	CreateAlarms bool `hcl:"create_alarms,optional"`
+
+	// Bake is how long to watch the alarms once all traffic is shifted.
+	Bake string `hcl:"bake,optional"`
+}
+
+type CodeDeployConfig struct {
+	// Application and DeploymentGroup default to the app name and are
+	// created if they don't exist.
+	Application     string `hcl:"application,optional"`
+	DeploymentGroup string `hcl:"deployment_group,optional"`
+
+	// DeploymentConfig is one of the predefined Lambda configs, such as
+	// Canary10Percent5Minutes, Linear10PercentEvery1Minute or AllAtOnce.
+	DeploymentConfig string `hcl:"deployment_config,optional"`
+
+	// ServiceRoleArn is the role CodeDeploy assumes, required when the
+	// deployment group has to be created.
+	ServiceRoleArn string `hcl:"service_role_arn,optional"`
+
+	// Functions invoked before and after traffic is shifted.
+	PreTrafficHook  string `hcl:"pre_traffic_hook,optional"`
+	PostTrafficHook string `hcl:"post_traffic_hook,optional"`
 }
 
+const (
+	// The alias managed by the release when no name is given.
+	DefaultAliasName = "live"
+
+	// How long each traffic shifting step bakes for.
+	DefaultShiftInterval = time.Minute
+
+	// How long a release is watched for alarms once all traffic is shifted.
+	DefaultBakeDuration = 5 * time.Minute
+
+	// The CodeDeploy config used when none is given.
+	DefaultDeploymentConfig = "AllAtOnce"
+)
+
 type ReleaseManager struct {
 	config ReleaseConfig
 }
@@ -32,12 +173,122 @@ func (rm *ReleaseManager) Config() (interface{}, error) {
 
 // Implement ConfigurableNotify
 func (rm *ReleaseManager) ConfigSet(config interface{}) error {
-	_, ok := config.(*ReleaseConfig)
+	c, ok := config.(*ReleaseConfig)
 	if !ok {
 		// The Waypoint SDK should ensure this never gets hit
 		return fmt.Errorf("Expected *ReleaseConfig as parameter")
 	}
 
+	if err := c.validateRules(); err != nil {
+		return err
+	}
+
+	if err := c.validateDelivery(); err != nil {
+		return err
+	}
+
+	if c.SQS != nil {
+		if err := c.SQS.validate(); err != nil {
+			return err
+		}
+	}
+
+	streams := map[string]bool{}
+	for _, s := range c.Streams {
+		if err := s.validate(); err != nil {
+			return err
+		}
+
+		if streams[s.SourceArn] {
+			return fmt.Errorf("stream %s is defined more than once", s.SourceArn)
+		}
+		streams[s.SourceArn] = true
+	}
+
+	buckets := map[string]bool{}
+	for _, b := range c.S3 {
+		if err := b.validate(); err != nil {
+			return err
+		}
+
+		// The app has a single entry in each bucket's notifications
+		if buckets[b.Bucket] {
+			return fmt.Errorf("s3 bucket %s is defined more than once", b.Bucket)
+		}
+		buckets[b.Bucket] = true
+	}
+
+	topics := map[string]bool{}
+	for _, t := range c.SNS {
+		if err := t.validate(); err != nil {
+			return err
+		}
+
+		if topics[t.TopicArn] {
+			return fmt.Errorf("sns topic %s is defined more than once", t.TopicArn)
+		}
+		topics[t.TopicArn] = true
+	}
+
+	if c.HTTPAPI != nil {
+		if err := c.HTTPAPI.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.ALB != nil {
+		if err := c.ALB.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.CodeDeploy != nil {
+		// CodeDeploy shifts traffic on an alias, so make sure there is one
+		if c.Alias == nil {
+			c.Alias = &AliasConfig{}
+		}
+
+		if c.CodeDeploy.DeploymentConfig == "" {
+			c.CodeDeploy.DeploymentConfig = DefaultDeploymentConfig
+		}
+	}
+
+	if c.Alias != nil {
+		if c.Alias.Name == "" {
+			c.Alias.Name = DefaultAliasName
+		}
+
+		if c.Alias.Interval != "" {
+			if _, err := time.ParseDuration(c.Alias.Interval); err != nil {
+				return fmt.Errorf("invalid alias interval %q: %s", c.Alias.Interval, err)
+			}
+		}
+
+		var last int64
+		for _, w := range c.Alias.Weights {
+			if w <= last || w > 100 {
+				return fmt.Errorf("alias weights must be increasing percentages between 1 and 100")
+			}
+			last = w
+		}
+
+		if last != 100 {
+			c.Alias.Weights = append(c.Alias.Weights, 100)
+		}
+	}
+
+	if c.Rollback != nil {
+		if len(c.Rollback.Alarms) == 0 && !c.Rollback.CreateAlarms {
+			return fmt.Errorf("rollback requires alarms or create_alarms to be set")
+		}
+
+		if c.Rollback.Bake != "" {
+			if _, err := time.ParseDuration(c.Rollback.Bake); err != nil {
+				return fmt.Errorf("invalid rollback bake %q: %s", c.Rollback.Bake, err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -52,6 +303,7 @@ func (rm *ReleaseManager) release(
 	log hclog.Logger,
 	ui terminal.UI,
 	src *component.Source,
+	job *component.JobInfo,
 	deploy *platform.Deployment,
 ) (*Release, error) {
 
@@ -79,71 +331,438 @@ func (rm *ReleaseManager) release(
 		return nil, err
 	}
 
+	configs, err := rm.rules(src)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check every pattern before anything is changed
+	var rules []*ruleRelease
+	for _, r := range configs {
+		rr := &ruleRelease{
+			RuleConfig:  r,
+			retryPolicy: rm.retryPolicy(),
+		}
+
+		if r.Schedule == "" {
+			rr.pattern, err = r.eventPattern(src)
+			if err != nil {
+				return nil, errors.Wrapf(err, "rule %s", r.Name)
+			}
+		}
+
+		rules = append(rules, rr)
+	}
+
 	step.Done()
 
-	//TODO: add flag for create_rule.. by default assume the role already exists
-	//and if so don't create or delete the rule
-	step = sg.Add("Creating EventBridge Rule")
+	for _, r := range rules {
+		switch {
+		case r.Schedule != "":
+			step = sg.Add("Checking schedule of rule %s: %s", r.Name, r.Schedule)
+
+			sched, err := schedule.Parse(r.Schedule)
+			if err != nil {
+				return nil, err
+			}
+
+			if _, ok := sched.(*schedule.Rate); ok {
+				fmt.Fprintln(step.TermOutput(), "Rate schedules count from when the rule is created, so these times are estimates")
+			}
+
+			for _, t := range schedule.NextN(sched, time.Now(), 5) {
+				fmt.Fprintf(step.TermOutput(), "Next run: %s\n", t.Format(time.RFC1123))
+			}
+
+			step.Done()
+
+		case r.TestEvents != "":
+			step = sg.Add("Testing event pattern of rule %s against sample events", r.Name)
+
+			if err := r.testEvents(step, src, r.pattern); err != nil {
+				return nil, err
+			}
+
+			step.Done()
+		}
+	}
+
+	if rm.config.PlanOnly {
+		return nil, rm.plan(ctx, sg, ui, sess, src, deploy, rules)
+	}
+
+	lamSvc := lambda.New(sess)
+
+	// By default the targets invoke the published version directly; with an
+	// alias configured they invoke the alias and traffic is shifted on it.
+	targetArn := deploy.VerArn
+
+	var alias *lambda.AliasConfiguration
+	if rm.config.Alias != nil {
+		step = sg.Add("Reading Lambda alias: %s", rm.config.Alias.Name)
+
+		alias, err = rm.ensureAlias(ctx, lamSvc, deploy)
+		if err != nil {
+			return nil, err
+		}
+
+		targetArn = *alias.AliasArn
+
+		step.Update("Using Lambda alias: %s", targetArn)
+		step.Done()
+	}
 
 	evSvc := eventbridge.New(sess)
+	cwSvc := cloudwatch.New(sess)
+
+	for _, r := range rules {
+		//TODO: add flag for create_rule.. by default assume the role already exists
+		//and if so don't create or delete the rule
+		step = sg.Add("Creating EventBridge rule: %s", r.Name)
+
+		ruleInput := &eventbridge.PutRuleInput{
+			Name:         aws.String(r.Name),
+			EventBusName: aws.String(r.EventBus),
+			State:        aws.String("ENABLED"),
+		}
+
+		if r.Schedule != "" {
+			ruleInput.ScheduleExpression = aws.String(r.Schedule)
+		} else {
+			ruleInput.EventPattern = aws.String(r.pattern)
+		}
+
+		rule, err := evSvc.PutRuleWithContext(ctx, ruleInput)
+		if err != nil {
+			return nil, err
+		}
+
+		r.arn = *rule.RuleArn
+
+		step.Update("Created EventBridge rule: %s", r.arn)
+		step.Done()
+
+		step = sg.Add("Updating Lambda function version permissions for rule %s", r.Name)
+
+		if err := r.grant(ctx, lamSvc, targetArn); err != nil {
+			return nil, err
+		}
+
+		step.Update("Lambda function version permissions updated")
+		step.Done()
+
+		// Remember what the target invoked before this release so that a
+		// failed bake can point it back.
+		r.previousArn, err = r.currentTarget(ctx, evSvc, src)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	rule, err := evSvc.PutRule(&eventbridge.PutRuleInput{
-		Name:         aws.String(src.App),
-		EventBusName: rm.config.EventBus,
-		EventPattern: aws.String(fmt.Sprintf("{\"source\": [\"%s\"]}", *rm.config.EventSource)),
-		State:        aws.String("ENABLED"),
-	})
+	watcher := &alarmWatcher{cwSvc: cwSvc}
+	if rm.config.Rollback != nil {
+		step = sg.Add("Preparing CloudWatch alarms for rollback")
 
-	if err != nil {
-		return nil, err
+		watcher.alarms = aws.StringSlice(rm.config.Rollback.Alarms)
+
+		if rm.config.Rollback.CreateAlarms {
+			created, err := rm.createAlarms(ctx, cwSvc, src, job, deploy)
+			if err != nil {
+				return nil, err
+			}
+
+			watcher.alarms = append(watcher.alarms, created...)
+			release.ManagedAlarms = aws.StringValueSlice(created)
+		}
+
+		step.Update("Watching %d CloudWatch alarms", len(watcher.alarms))
+		step.Done()
 	}
 
-	step.Update("Created EventBridge rule: %s", *rule.RuleArn)
+	if dlq := rm.config.DeadLetterQueue; dlq != nil && len(rules) > 0 {
+		step = sg.Add("Preparing dead-letter queue")
 
-	step.Done()
+		dlqArn := dlq.Arn
+		if dlqArn == "" {
+			var ruleArns []string
+			for _, r := range rules {
+				ruleArns = append(ruleArns, r.arn)
+			}
 
-	step = sg.Add("Updating Lambda function version permissions")
+			dlqArn, release.ManagedDeadLetterQueue, err = rm.ensureDeadLetterQueue(ctx, sqs.New(sess), src, ruleArns)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-	lamSvc := lambda.New(sess)
+		for _, r := range rules {
+			r.deadLetterArn = dlqArn
+		}
 
-	_, err = lamSvc.AddPermission(&lambda.AddPermissionInput{
-		StatementId:  aws.String(fmt.Sprintf("lambda-eventbridge-%s", src.App)),
-		FunctionName: aws.String(deploy.VerArn),
-		Action:       aws.String("lambda:InvokeFunction"),
-		Principal:    aws.String("events.amazonaws.com"),
-		SourceArn:    rule.RuleArn,
-	})
+		step.Update("Sending undeliverable events to %s", dlqArn)
+		step.Done()
+	}
 
-	if err != nil {
-		return nil, err
+	retargeted := false
+	for _, r := range rules {
+		step = sg.Add("Creating EventBridge target for rule %s", r.Name)
+
+		_, err = evSvc.PutTargetsWithContext(ctx, &eventbridge.PutTargetsInput{
+			Rule:         aws.String(r.Name),
+			EventBusName: aws.String(r.EventBus),
+			Targets:      []*eventbridge.Target{r.eventTarget(src, targetArn)},
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if r.previousArn != targetArn {
+			retargeted = true
+		}
+
+		step.Update("Created EventBridge target for rule %s", r.Name)
+		step.Done()
 	}
 
-	step.Update("Lambda function version permissions updated")
+	var mappings []*mappingRelease
+	for _, s := range rm.mappingSources() {
+		create, update := s.mappingInputs(targetArn)
 
-	step.Done()
+		step = sg.Add("Connecting %s: %s", s.kind(), aws.StringValue(create.EventSourceArn))
 
-	step = sg.Add("Creating EventBridge target for Lambda function version")
+		m, err := ensureMapping(ctx, lamSvc, s.kind(), create, update)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, m)
 
-	_, err = evSvc.PutTargets(&eventbridge.PutTargetsInput{
-		Rule: aws.String(src.App),
-		Targets: []*eventbridge.Target{
-			{
-				Id:        aws.String(src.App),
-				Arn:       aws.String(deploy.VerArn),
-				InputPath: aws.String("$.detail"),
-			},
-		},
-	})
+		if m.previousArn != targetArn {
+			retargeted = true
+		}
 
-	if err != nil {
-		return nil, err
+		step.Update("Connected %s %s to %s", s.kind(), m.sourceArn, targetArn)
+		step.Done()
 	}
 
-	step.Update("Created EventBridge rule target for Lambda function version")
-	step.Done()
+	var buckets []*bucketRelease
+	if len(rm.config.S3) > 0 {
+		s3Svc := s3.New(sess)
+
+		for _, c := range rm.config.S3 {
+			step = sg.Add("Subscribing to S3 bucket: %s", c.Bucket)
+
+			b, err := rm.subscribeBucket(ctx, lamSvc, s3Svc, src, c, targetArn)
+			if err != nil {
+				return nil, err
+			}
+			buckets = append(buckets, b)
+
+			if b.previousArn() != targetArn {
+				retargeted = true
+			}
+
+			step.Update("Subscribed %s to S3 bucket %s", targetArn, c.Bucket)
+			step.Done()
+		}
+	}
+
+	var subscriptions []*subscriptionRelease
+	if len(rm.config.SNS) > 0 {
+		snsSvc := sns.New(sess)
+
+		for _, c := range rm.config.SNS {
+			step = sg.Add("Subscribing to SNS topic: %s", c.TopicArn)
+
+			s, err := rm.subscribeTopic(ctx, lamSvc, snsSvc, c, targetArn)
+			if err != nil {
+				return nil, err
+			}
+			subscriptions = append(subscriptions, s)
+
+			if s.previousArn != targetArn {
+				retargeted = true
+			}
+
+			step.Update("Subscribed %s to SNS topic %s", targetArn, c.TopicArn)
+			step.Done()
+		}
+	}
+
+	var api *apiRelease
+	if rm.config.HTTPAPI != nil {
+		step = sg.Add("Releasing HTTP API: %s", rm.config.HTTPAPI.name(src))
+
+		api, err = rm.releaseHTTPAPI(ctx, lamSvc, apigatewayv2.New(sess), src, targetArn)
+		if err != nil {
+			return nil, err
+		}
+
+		if api.previousArn != targetArn {
+			retargeted = true
+		}
+
+		if api.domainTarget != "" {
+			fmt.Fprintf(step.TermOutput(), "Point the DNS record of %s at %s\n", api.domainName, api.domainTarget)
+		}
+
+		step.Update("HTTP API serving %s at %s", targetArn, api.url())
+		step.Done()
+	}
+
+	var alb *albRelease
+	var albUrl string
+	if rm.config.ALB != nil {
+		step = sg.Add("Registering with load balancer: %s", rm.config.ALB.ListenerArn)
+
+		elbSvc := elbv2.New(sess)
+
+		alb, err = rm.releaseALB(ctx, lamSvc, elbSvc, src, targetArn)
+		if err != nil {
+			return nil, err
+		}
+
+		if alb.previousArn != targetArn {
+			retargeted = true
+		}
+
+		albUrl, err = alb.url(ctx, elbSvc)
+		if err != nil {
+			return nil, err
+		}
+
+		step.Update("Load balancer serving %s at %s", targetArn, albUrl)
+		step.Done()
+	}
+
+	moved := &triggers{
+		rules:         rules,
+		mappings:      mappings,
+		buckets:       buckets,
+		subscriptions: subscriptions,
+		api:           api,
+		alb:           alb,
+	}
+
+	if alias != nil {
+		if rm.config.CodeDeploy != nil {
+			release.DeploymentId, err = rm.codeDeploy(ctx, sg, codedeploy.New(sess), src, alias, deploy)
+		} else {
+			err = rm.shiftTraffic(ctx, sg, lamSvc, watcher, alias, deploy)
+		}
+		if err == nil {
+			err = rm.bake(ctx, sg, watcher)
+		}
+		if err != nil {
+			if aerr, ok := err.(*alarmError); ok {
+				return nil, rm.rollback(ctx, sg, sess, src, deploy, alias, moved, aerr)
+			}
+			return nil, err
+		}
+
+		release.Alias = *alias.Name
+		if rm.config.CodeDeploy == nil {
+			release.Weights = rm.config.Alias.Weights
+		}
+
+		if prev := aws.StringValue(alias.FunctionVersion); prev != deploy.Version {
+			release.PreviousVersion = prev
+		}
+	} else if retargeted {
+		err = rm.bake(ctx, sg, watcher)
+		if err != nil {
+			if aerr, ok := err.(*alarmError); ok {
+				return nil, rm.rollback(ctx, sg, sess, src, deploy, nil, moved, aerr)
+			}
+			return nil, err
+		}
+	}
+
+	// What the triggers invoked before this release no longer needs their
+	// permission
+	for _, r := range moved.stale(targetArn) {
+		if err := revokeSource(ctx, lamSvc, r.arn, r.source); err != nil {
+			log.Warn("unable to revoke permission of previous version", "source", r.source, "error", err)
+		}
+	}
+
+	if rm.config.Rollback != nil && rm.config.Rollback.CreateAlarms {
+		err = rm.pruneAlarms(ctx, cwSvc, src, job, watcher.alarms)
+		if err != nil {
+			log.Warn("unable to delete alarms for previous versions", "error", err)
+		}
+	}
+
+	for _, r := range rules {
+		release.Rules = append(release.Rules, &Rule{
+			Name:      r.Name,
+			EventBus:  r.EventBus,
+			RuleArn:   r.arn,
+			TargetArn: targetArn,
+		})
+	}
+
+	for _, m := range mappings {
+		release.EventSourceMappings = append(release.EventSourceMappings, &EventSourceMapping{
+			Uuid:           m.uuid,
+			EventSourceArn: m.sourceArn,
+			FunctionArn:    targetArn,
+			Enabled:        m.enabled,
+		})
+	}
+
+	for _, b := range buckets {
+		release.BucketNotifications = append(release.BucketNotifications, &BucketNotification{
+			Bucket:      b.Bucket,
+			Account:     b.account,
+			Id:          notificationId(src),
+			FunctionArn: targetArn,
+		})
+	}
+
+	for _, s := range subscriptions {
+		release.Subscriptions = append(release.Subscriptions, &Subscription{
+			TopicArn:        s.TopicArn,
+			SubscriptionArn: s.arn,
+			FunctionArn:     targetArn,
+		})
+	}
+
+	release.Url = rm.config.Url
+
+	if api != nil {
+		release.HttpApi = &HttpApi{
+			ApiId:         aws.StringValue(api.api.ApiId),
+			IntegrationId: api.integrationId,
+			FunctionArn:   targetArn,
+			Managed:       api.managed,
+			DomainName:    api.domainName,
+			ApiMappingId:  api.mappingId,
+			ManagedDomain: api.managedDomain,
+		}
+
+		if release.Url == "" {
+			release.Url = api.url()
+		}
+	}
+
+	if alb != nil {
+		release.Alb = &AlbTarget{
+			TargetGroupArn:     alb.targetGroupArn,
+			FunctionArn:        targetArn,
+			RuleArn:            alb.ruleArn,
+			ManagedRule:        alb.managedRule,
+			ManagedTargetGroup: alb.managedTargetGroup,
+		}
+
+		if release.Url == "" {
+			release.Url = albUrl
+		}
+	}
 
-	release.EventSource = *rm.config.EventSource
-	release.FunctionArn = deploy.VerArn
+	release.EventSource = aws.StringValue(rm.config.EventSource)
+	release.Schedule = rm.config.Schedule
+	release.FunctionArn = targetArn
 
 	return release, nil
 }
@@ -156,6 +775,7 @@ func (rm *ReleaseManager) destroy(ctx context.Context,
 	log hclog.Logger,
 	ui terminal.UI,
 	src *component.Source,
+	release *Release,
 ) error {
 	// We'll update the user in real time
 	st := ui.Status()
@@ -170,29 +790,171 @@ func (rm *ReleaseManager) destroy(ctx context.Context,
 	}
 
 	evSvc := eventbridge.New(sess)
-	_, err = evSvc.RemoveTargets(&eventbridge.RemoveTargetsInput{
-		Rule: aws.String(src.App),
-		Ids: []*string{
-			aws.String(src.App),
-		},
-	})
+	lamSvc := lambda.New(sess)
 
-	if err != nil {
-		return err
+	for _, r := range rm.releasedRules(src, release) {
+		st.Update(fmt.Sprintf("Deleting EventBridge rule %s", r.Name))
+
+		_, err = evSvc.RemoveTargetsWithContext(ctx, &eventbridge.RemoveTargetsInput{
+			Rule:         aws.String(r.Name),
+			EventBusName: aws.String(r.EventBus),
+			Ids: []*string{
+				aws.String(src.App),
+			},
+		})
+
+		if err != nil {
+			return err
+		}
+
+		_, err = evSvc.DeleteRuleWithContext(ctx, &eventbridge.DeleteRuleInput{
+			Name:         aws.String(r.Name),
+			EventBusName: aws.String(r.EventBus),
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if r.RuleArn != "" {
+			err = revokeSource(ctx, lamSvc, r.TargetArn, r.RuleArn)
+			if err != nil {
+				return err
+			}
+		}
+
+		st.Step(terminal.StatusOK, fmt.Sprintf("Deleted EventBridge rule %s", r.Name))
 	}
 
-	st.Step(terminal.StatusOK, "Deleted EventBridge Target")
+	for _, m := range release.EventSourceMappings {
+		st.Update(fmt.Sprintf("Deleting event source mapping of %s", m.EventSourceArn))
 
-	st.Update("Deleting EventBridge rule")
-	_, err = evSvc.DeleteRule(&eventbridge.DeleteRuleInput{
-		Name: aws.String(src.App),
-	})
+		_, err = lamSvc.DeleteEventSourceMappingWithContext(ctx, &lambda.DeleteEventSourceMappingInput{
+			UUID: aws.String(m.Uuid),
+		})
 
-	if err != nil {
-		return err
+		if err != nil && !isCode(err, lambda.ErrCodeResourceNotFoundException) {
+			return err
+		}
+
+		st.Step(terminal.StatusOK, fmt.Sprintf("Deleted event source mapping of %s", m.EventSourceArn))
+	}
+
+	s3Svc := s3.New(sess)
+
+	for _, b := range release.BucketNotifications {
+		st.Update(fmt.Sprintf("Unsubscribing from S3 bucket %s", b.Bucket))
+
+		_, err = putEntry(ctx, s3Svc, b.Bucket, b.Account, b.Id, nil)
+		if err != nil && !isCode(errors.Cause(err), s3.ErrCodeNoSuchBucket) {
+			return err
+		}
+
+		_, err = lamSvc.RemovePermissionWithContext(ctx, &lambda.RemovePermissionInput{
+			FunctionName: aws.String(b.FunctionArn),
+			StatementId:  aws.String((&S3Config{Bucket: b.Bucket}).statementId()),
+		})
+
+		if err != nil && !isCode(err, lambda.ErrCodeResourceNotFoundException) {
+			return err
+		}
+
+		st.Step(terminal.StatusOK, fmt.Sprintf("Unsubscribed from S3 bucket %s", b.Bucket))
+	}
+
+	snsSvc := sns.New(sess)
+
+	for _, s := range release.Subscriptions {
+		st.Update(fmt.Sprintf("Unsubscribing from SNS topic %s", s.TopicArn))
+
+		_, err = snsSvc.UnsubscribeWithContext(ctx, &sns.UnsubscribeInput{
+			SubscriptionArn: aws.String(s.SubscriptionArn),
+		})
+
+		if err != nil && !isCode(err, sns.ErrCodeNotFoundException) {
+			return err
+		}
+
+		_, err = lamSvc.RemovePermissionWithContext(ctx, &lambda.RemovePermissionInput{
+			FunctionName: aws.String(s.FunctionArn),
+			StatementId:  aws.String((&SNSConfig{TopicArn: s.TopicArn}).statementId()),
+		})
+
+		if err != nil && !isCode(err, lambda.ErrCodeResourceNotFoundException) {
+			return err
+		}
+
+		st.Step(terminal.StatusOK, fmt.Sprintf("Unsubscribed from SNS topic %s", s.TopicArn))
+	}
+
+	if api := release.HttpApi; api != nil {
+		st.Update(fmt.Sprintf("Deleting HTTP API %s", api.ApiId))
+
+		err = deleteHTTPAPI(ctx, apigatewayv2.New(sess), api)
+		if err != nil {
+			return err
+		}
+
+		_, err = lamSvc.RemovePermissionWithContext(ctx, &lambda.RemovePermissionInput{
+			FunctionName: aws.String(api.FunctionArn),
+			StatementId:  aws.String(apiStatementId(api.ApiId)),
+		})
+
+		if err != nil && !isCode(err, lambda.ErrCodeResourceNotFoundException) {
+			return err
+		}
+
+		st.Step(terminal.StatusOK, fmt.Sprintf("Deleted HTTP API %s", api.ApiId))
+	}
+
+	if alb := release.Alb; alb != nil {
+		st.Update(fmt.Sprintf("Deregistering from target group %s", alb.TargetGroupArn))
+
+		err = deleteALB(ctx, elbv2.New(sess), alb)
+		if err != nil {
+			return err
+		}
+
+		_, err = lamSvc.RemovePermissionWithContext(ctx, &lambda.RemovePermissionInput{
+			FunctionName: aws.String(alb.FunctionArn),
+			StatementId:  aws.String(albStatementId(alb.TargetGroupArn)),
+		})
+
+		if err != nil && !isCode(err, lambda.ErrCodeResourceNotFoundException) {
+			return err
+		}
+
+		st.Step(terminal.StatusOK, fmt.Sprintf("Deregistered from target group %s", alb.TargetGroupArn))
 	}
 
-	st.Step(terminal.StatusOK, "Deleted EventBridge rule")
+	if len(release.ManagedAlarms) > 0 {
+		st.Update("Deleting CloudWatch alarms")
+
+		cwSvc := cloudwatch.New(sess)
+		_, err = cwSvc.DeleteAlarmsWithContext(ctx, &cloudwatch.DeleteAlarmsInput{
+			AlarmNames: aws.StringSlice(release.ManagedAlarms),
+		})
+
+		if err != nil {
+			return err
+		}
+
+		st.Step(terminal.StatusOK, "Deleted CloudWatch alarms")
+	}
+
+	if release.ManagedDeadLetterQueue != "" {
+		st.Update("Deleting dead-letter queue")
+
+		_, err = sqs.New(sess).DeleteQueueWithContext(ctx, &sqs.DeleteQueueInput{
+			QueueUrl: aws.String(release.ManagedDeadLetterQueue),
+		})
+
+		if err != nil && !isCode(err, sqs.ErrCodeQueueDoesNotExist) {
+			return err
+		}
+
+		st.Step(terminal.StatusOK, "Deleted dead-letter queue")
+	}
 
 	return err
 }
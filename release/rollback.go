@@ -0,0 +1,403 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigatewayv2"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/phoban01/lambda-ext/platform"
+	"github.com/pkg/errors"
+)
+
+// How often alarm states are polled while a release bakes.
+const alarmPollInterval = 15 * time.Second
+
+// alarmError is returned when a watched alarm fires during a bake.
+type alarmError struct {
+	name   string
+	reason string
+}
+
+func (e *alarmError) Error() string {
+	return fmt.Sprintf("alarm %s is in ALARM state: %s", e.name, e.reason)
+}
+
+// alarmWatcher polls a set of CloudWatch alarms while a release bakes.
+type alarmWatcher struct {
+	cwSvc  *cloudwatch.CloudWatch
+	alarms []*string
+}
+
+// wait blocks for d, returning an *alarmError as soon as any watched alarm
+// enters the ALARM state.
+func (w *alarmWatcher) wait(ctx context.Context, d time.Duration) error {
+	deadline := time.After(d)
+
+	for {
+		if err := w.check(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return w.check(ctx)
+		case <-time.After(alarmPollInterval):
+		}
+	}
+}
+
+func (w *alarmWatcher) check(ctx context.Context) error {
+	if len(w.alarms) == 0 {
+		return nil
+	}
+
+	out, err := w.cwSvc.DescribeAlarmsWithContext(ctx, &cloudwatch.DescribeAlarmsInput{
+		AlarmNames: w.alarms,
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "unable to read CloudWatch alarms")
+	}
+
+	for _, a := range out.MetricAlarms {
+		if aws.StringValue(a.StateValue) == cloudwatch.StateValueAlarm {
+			return &alarmError{
+				name:   aws.StringValue(a.AlarmName),
+				reason: aws.StringValue(a.StateReason),
+			}
+		}
+	}
+
+	return nil
+}
+
+// bake watches the alarms for the configured bake duration once all traffic
+// has been shifted to the new version.
+func (rm *ReleaseManager) bake(
+	ctx context.Context,
+	sg terminal.StepGroup,
+	watcher *alarmWatcher,
+) error {
+	if rm.config.Rollback == nil {
+		return nil
+	}
+
+	d := DefaultBakeDuration
+	if rm.config.Rollback.Bake != "" {
+		d, _ = time.ParseDuration(rm.config.Rollback.Bake)
+	}
+
+	step := sg.Add("Baking release for %s", d)
+	defer step.Abort()
+
+	if err := watcher.wait(ctx, d); err != nil {
+		return err
+	}
+
+	step.Update("No alarms fired while baking release")
+	step.Done()
+
+	return nil
+}
+
+// alarmPrefix is the name prefix of all alarms created for an app in a
+// workspace.
+func alarmPrefix(src *component.Source, job *component.JobInfo) string {
+	return fmt.Sprintf("waypoint-%s-%s-", src.App, job.Workspace)
+}
+
+// alarmSuffix matches what follows the prefix in the name of a created alarm,
+// telling them apart from those of workspaces whose name extends this one's.
+var alarmSuffix = regexp.MustCompile(`^v[0-9]+-(errors|throttles)$`)
+
+// createAlarms creates Errors and Throttles alarms scoped to the deployed
+// version and returns their names.
+func (rm *ReleaseManager) createAlarms(
+	ctx context.Context,
+	cwSvc *cloudwatch.CloudWatch,
+	src *component.Source,
+	job *component.JobInfo,
+	deploy *platform.Deployment,
+) ([]*string, error) {
+	// Invocations through an alias are reported against the alias, with the
+	// version that ran in the ExecutedVersion dimension.
+	dimensions := []*cloudwatch.Dimension{
+		{Name: aws.String("FunctionName"), Value: aws.String(src.App)},
+		{Name: aws.String("Resource"), Value: aws.String(src.App + ":" + deploy.Version)},
+	}
+
+	if rm.config.Alias != nil {
+		dimensions = []*cloudwatch.Dimension{
+			{Name: aws.String("FunctionName"), Value: aws.String(src.App)},
+			{Name: aws.String("Resource"), Value: aws.String(src.App + ":" + rm.config.Alias.Name)},
+			{Name: aws.String("ExecutedVersion"), Value: aws.String(deploy.Version)},
+		}
+	}
+
+	var names []*string
+	for _, metric := range []string{"Errors", "Throttles"} {
+		name := fmt.Sprintf("%sv%s-%s", alarmPrefix(src, job), deploy.Version, strings.ToLower(metric))
+
+		_, err := cwSvc.PutMetricAlarmWithContext(ctx, &cloudwatch.PutMetricAlarmInput{
+			AlarmName:          aws.String(name),
+			AlarmDescription:   aws.String(fmt.Sprintf("waypoint %s version %s %s", src.App, deploy.Version, metric)),
+			Namespace:          aws.String("AWS/Lambda"),
+			MetricName:         aws.String(metric),
+			Dimensions:         dimensions,
+			Statistic:          aws.String(cloudwatch.StatisticSum),
+			Period:             aws.Int64(60),
+			EvaluationPeriods:  aws.Int64(1),
+			Threshold:          aws.Float64(1),
+			ComparisonOperator: aws.String(cloudwatch.ComparisonOperatorGreaterThanOrEqualToThreshold),
+			TreatMissingData:   aws.String("notBreaching"),
+		})
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to create alarm %s", name)
+		}
+
+		names = append(names, aws.String(name))
+	}
+
+	return names, nil
+}
+
+// pruneAlarms deletes alarms created for previous versions of the app in the
+// workspace, keeping the ones in keep.
+func (rm *ReleaseManager) pruneAlarms(
+	ctx context.Context,
+	cwSvc *cloudwatch.CloudWatch,
+	src *component.Source,
+	job *component.JobInfo,
+	keep []*string,
+) error {
+	current := map[string]bool{}
+	for _, k := range keep {
+		current[*k] = true
+	}
+
+	prefix := alarmPrefix(src, job)
+
+	var stale []*string
+	err := cwSvc.DescribeAlarmsPagesWithContext(ctx, &cloudwatch.DescribeAlarmsInput{
+		AlarmNamePrefix: aws.String(prefix),
+	}, func(out *cloudwatch.DescribeAlarmsOutput, last bool) bool {
+		for _, a := range out.MetricAlarms {
+			name := aws.StringValue(a.AlarmName)
+			if !current[name] && alarmSuffix.MatchString(strings.TrimPrefix(name, prefix)) {
+				stale = append(stale, a.AlarmName)
+			}
+		}
+		return true
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// DeleteAlarms takes at most 100 names at a time
+	for len(stale) > 0 {
+		n := len(stale)
+		if n > 100 {
+			n = 100
+		}
+
+		_, err = cwSvc.DeleteAlarmsWithContext(ctx, &cloudwatch.DeleteAlarmsInput{
+			AlarmNames: stale[:n],
+		})
+		if err != nil {
+			return err
+		}
+
+		stale = stale[n:]
+	}
+
+	return nil
+}
+
+// triggers are what the release points at the function, remembered so that
+// a failed bake can point them back.
+type triggers struct {
+	rules         []*ruleRelease
+	mappings      []*mappingRelease
+	buckets       []*bucketRelease
+	subscriptions []*subscriptionRelease
+	api           *apiRelease
+	alb           *albRelease
+}
+
+// restore points every trigger back at what it invoked before the release.
+func (t *triggers) restore(ctx context.Context, sess *session.Session, src *component.Source) error {
+	evSvc := eventbridge.New(sess)
+	for _, r := range t.rules {
+		if err := r.restoreTarget(ctx, evSvc, src); err != nil {
+			return err
+		}
+	}
+
+	lamSvc := lambda.New(sess)
+	for _, m := range t.mappings {
+		if err := m.restore(ctx, lamSvc); err != nil {
+			return err
+		}
+	}
+
+	s3Svc := s3.New(sess)
+	for _, b := range t.buckets {
+		if err := b.restore(ctx, s3Svc, src); err != nil {
+			return err
+		}
+	}
+
+	snsSvc := sns.New(sess)
+	for _, s := range t.subscriptions {
+		if err := s.restore(ctx, snsSvc); err != nil {
+			return err
+		}
+	}
+
+	if t.api != nil {
+		if err := t.api.restore(ctx, apigatewayv2.New(sess)); err != nil {
+			return err
+		}
+	}
+
+	if t.alb != nil {
+		return t.alb.restore(ctx, elbv2.New(sess))
+	}
+
+	return nil
+}
+
+// revocation is a permission letting source invoke arn.
+type revocation struct {
+	arn, source string
+}
+
+// stale returns the permissions letting the triggers invoke what they invoked
+// before the release, which a successful release no longer needs.
+func (t *triggers) stale(targetArn string) []revocation {
+	var stale []revocation
+	add := func(previousArn, source string) {
+		// Permissions of other functions are left alone
+		if previousArn != "" && previousArn != targetArn && unqualified(previousArn) == unqualified(targetArn) {
+			stale = append(stale, revocation{arn: previousArn, source: source})
+		}
+	}
+
+	for _, r := range t.rules {
+		add(r.previousArn, r.arn)
+	}
+
+	for _, b := range t.buckets {
+		add(b.previousArn(), bucketArn(targetArn, b.Bucket))
+	}
+
+	// A subscription that already delivered to the target may still have
+	// replaced others
+	for _, s := range t.subscriptions {
+		for _, endpoint := range s.replaced {
+			add(endpoint, s.TopicArn)
+		}
+	}
+
+	if t.api != nil {
+		add(t.api.previousArn, apiSourceArn(targetArn, aws.StringValue(t.api.api.ApiId)))
+	}
+
+	if t.alb != nil {
+		add(t.alb.previousArn, t.alb.targetGroupArn)
+	}
+
+	return stale
+}
+
+// rollback points the alias, or failing that the triggers, back at what was
+// serving before this release and returns an error describing why.
+func (rm *ReleaseManager) rollback(
+	ctx context.Context,
+	sg terminal.StepGroup,
+	sess *session.Session,
+	src *component.Source,
+	deploy *platform.Deployment,
+	alias *lambda.AliasConfiguration,
+	moved *triggers,
+	cause *alarmError,
+) error {
+	step := sg.Add("Rolling back release: %s", cause)
+	defer step.Abort()
+
+	// The release may have been cancelled, but the rollback must still run.
+	ctx = context.Background()
+
+	var err error
+	var to string
+
+	switch {
+	case alias != nil && aws.StringValue(alias.FunctionVersion) != deploy.Version:
+		to = "version " + aws.StringValue(alias.FunctionVersion)
+
+		_, err = lambda.New(sess).UpdateAliasWithContext(ctx, &lambda.UpdateAliasInput{
+			FunctionName:    aws.String(deploy.FuncArn),
+			Name:            alias.Name,
+			FunctionVersion: alias.FunctionVersion,
+			RoutingConfig: &lambda.AliasRoutingConfiguration{
+				AdditionalVersionWeights: map[string]*float64{},
+			},
+		})
+
+	default:
+		to = "the previous EventBridge targets and event sources"
+		err = moved.restore(ctx, sess, src)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "%s; rolling back to %s failed", cause, to)
+	}
+
+	step.Update("Rolled back release to %s", to)
+	step.Status(terminal.StatusWarn)
+	step.Done()
+
+	return fmt.Errorf("release of version %s rolled back to %s: %s", deploy.Version, to, cause)
+}
+
+// restoreTarget points the app's target on the rule back at what it invoked
+// before the release, or removes it if there was nothing.
+func (r *ruleRelease) restoreTarget(
+	ctx context.Context,
+	evSvc *eventbridge.EventBridge,
+	src *component.Source,
+) error {
+	if r.previousArn != "" {
+		_, err := evSvc.PutTargetsWithContext(ctx, &eventbridge.PutTargetsInput{
+			Rule:         aws.String(r.Name),
+			EventBusName: aws.String(r.EventBus),
+			Targets:      []*eventbridge.Target{r.eventTarget(src, r.previousArn)},
+		})
+		return err
+	}
+
+	// Nothing was released before, so stop invoking the new version.
+	_, err := evSvc.RemoveTargetsWithContext(ctx, &eventbridge.RemoveTargetsInput{
+		Rule:         aws.String(r.Name),
+		EventBusName: aws.String(r.EventBus),
+		Ids:          []*string{aws.String(src.App)},
+	})
+	return err
+}
@@ -0,0 +1,392 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/phoban01/lambda-ext/schedule"
+	"github.com/pkg/errors"
+)
+
+type RuleConfig struct {
+	// Name is the name of the EventBridge rule.
+	Name string `hcl:"name,label"`
+
+	EventBus    string  `hcl:"event_bus,optional"`
+	EventSource *string `hcl:"event_source,optional"`
+
+	// EventPattern is the full pattern of the rule, used instead of
+	// event_source.
+	EventPattern *EventPatternConfig `hcl:"event_pattern,block"`
+
+	// TestEvents is a directory, relative to the app, of sample events the
+	// pattern is checked against before the rule is changed. Events under
+	// its match directory must match and those under no_match must not.
+	TestEvents string `hcl:"test_events,optional"`
+
+	// Schedule invokes the function on a cron(...) or rate(...) expression
+	// instead of in response to events.
+	Schedule string `hcl:"schedule,optional"`
+
+	// Input is constant JSON passed to the function instead of the event.
+	Input string `hcl:"input,optional"`
+
+	// InputPath selects the part of the event passed to the function,
+	// "$.detail" by default. "$" passes the whole event.
+	InputPath string `hcl:"input_path,optional"`
+
+	// InputTransformer reshapes the event before it is passed on.
+	InputTransformer *InputTransformerConfig `hcl:"input_transformer,block"`
+}
+
+// ruleRelease is a rule being released.
+type ruleRelease struct {
+	*RuleConfig
+
+	// The validated event pattern, unless the rule runs on a schedule.
+	pattern string
+
+	arn string
+
+	// What the app's target on the rule invoked before the release.
+	previousArn string
+
+	// How the target delivers events, if not the EventBridge defaults.
+	retryPolicy   *eventbridge.RetryPolicy
+	deadLetterArn string
+}
+
+// The names EventBridge accepts for rules.
+var ruleName = regexp.MustCompile(`^[\.\-_A-Za-z0-9]{1,64}$`)
+
+func (r *RuleConfig) validate() error {
+	switch {
+	case r.Schedule != "":
+		if r.EventSource != nil || r.EventPattern != nil {
+			return fmt.Errorf("schedule can't be combined with event_source or event_pattern")
+		}
+
+		if _, err := schedule.Parse(r.Schedule); err != nil {
+			return err
+		}
+
+		// EventBridge only runs schedules on the default bus
+		if r.EventBus != "" && r.EventBus != "default" {
+			return fmt.Errorf("schedule can only be used on the default event bus")
+		}
+
+		if r.TestEvents != "" {
+			return fmt.Errorf("test_events can't be used with schedule")
+		}
+	case r.EventPattern != nil:
+		if r.EventSource != nil {
+			return fmt.Errorf("event_source can't be combined with event_pattern")
+		}
+
+		if err := r.EventPattern.validate(); err != nil {
+			return err
+		}
+	case r.EventSource == nil:
+		return fmt.Errorf("one of event_source, event_pattern or schedule is required")
+	}
+
+	shapes := 0
+	for _, set := range []bool{r.Input != "", r.InputPath != "", r.InputTransformer != nil} {
+		if set {
+			shapes++
+		}
+	}
+
+	if shapes > 1 {
+		return fmt.Errorf("only one of input, input_path and input_transformer can be set")
+	}
+
+	if r.Input != "" && !json.Valid([]byte(r.Input)) {
+		return fmt.Errorf("input is not valid JSON")
+	}
+
+	if r.InputPath != "" {
+		if err := validateInputPath(r.InputPath); err != nil {
+			return fmt.Errorf("input_path: %s", err)
+		}
+	}
+
+	if r.InputTransformer != nil {
+		if err := r.InputTransformer.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// legacyRule returns the rule described by the top-level event settings, if
+// any, named after the app once it is known.
+func (c *ReleaseConfig) legacyRule() *RuleConfig {
+	if c.EventSource == nil && c.EventPattern == nil && c.Schedule == "" {
+		return nil
+	}
+
+	return &RuleConfig{
+		EventBus:     aws.StringValue(c.EventBus),
+		EventSource:  c.EventSource,
+		EventPattern: c.EventPattern,
+		TestEvents:   c.TestEvents,
+		Schedule:     c.Schedule,
+
+		Input:            c.Input,
+		InputPath:        c.InputPath,
+		InputTransformer: c.InputTransformer,
+	}
+}
+
+// validateRules checks the top-level rule and each rule block.
+func (c *ReleaseConfig) validateRules() error {
+	legacy := c.legacyRule()
+
+	if legacy == nil && len(c.Rules) == 0 {
+		if c.TestEvents != "" || c.Input != "" || c.InputPath != "" || c.InputTransformer != nil {
+			return fmt.Errorf("test_events and input settings require event_source, event_pattern or schedule")
+		}
+
+		if c.SQS == nil && len(c.Streams) == 0 && len(c.S3) == 0 && len(c.SNS) == 0 && c.HTTPAPI == nil && c.ALB == nil {
+			return fmt.Errorf("one of event_source, event_pattern, schedule, a rule, sqs, stream, s3, sns, http_api or alb block is required")
+		}
+	}
+
+	if legacy != nil {
+		if err := legacy.validate(); err != nil {
+			return err
+		}
+	}
+
+	names := map[string]bool{}
+	for _, r := range c.Rules {
+		if !ruleName.MatchString(r.Name) {
+			return fmt.Errorf("rule name %q must be up to 64 letters, digits, '.', '-' or '_'", r.Name)
+		}
+
+		if names[r.Name] {
+			return fmt.Errorf("rule %q is defined more than once", r.Name)
+		}
+		names[r.Name] = true
+
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("rule %q: %s", r.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// rules returns every rule the release manages, starting with the one
+// described by the top-level settings, which is named after the app.
+func (rm *ReleaseManager) rules(src *component.Source) ([]*RuleConfig, error) {
+	var rules []*RuleConfig
+
+	legacy := rm.config.legacyRule()
+	if legacy != nil {
+		legacy.Name = src.App
+		rules = append(rules, legacy)
+	}
+
+	for _, r := range rm.config.Rules {
+		if legacy != nil && r.Name == legacy.Name {
+			return nil, fmt.Errorf("rule %q clashes with the rule named after the app", r.Name)
+		}
+		rules = append(rules, r)
+	}
+
+	for _, r := range rules {
+		if r.EventBus == "" {
+			r.EventBus = "default"
+		}
+	}
+
+	return rules, nil
+}
+
+// target is the EventBridge target of the rule invoking arn.
+func (r *RuleConfig) target(src *component.Source, arn string) *eventbridge.Target {
+	t := &eventbridge.Target{
+		Id:  aws.String(src.App),
+		Arn: aws.String(arn),
+	}
+
+	switch {
+	case r.InputTransformer != nil:
+		t.InputTransformer = &eventbridge.InputTransformer{
+			InputPathsMap: aws.StringMap(r.InputTransformer.InputPaths),
+			InputTemplate: aws.String(r.InputTransformer.InputTemplate),
+		}
+	case r.Input != "":
+		t.Input = aws.String(r.Input)
+	case r.InputPath != "":
+		t.InputPath = aws.String(r.InputPath)
+	case r.Schedule == "":
+		// Events are passed on as their detail by default. Scheduled events
+		// have no detail worth passing, so they are passed whole.
+		t.InputPath = aws.String("$.detail")
+	}
+
+	return t
+}
+
+// eventTarget is the rule's target invoking arn with the release's retry
+// policy and dead-letter queue.
+func (r *ruleRelease) eventTarget(src *component.Source, arn string) *eventbridge.Target {
+	t := r.target(src, arn)
+	t.RetryPolicy = r.retryPolicy
+
+	if r.deadLetterArn != "" {
+		t.DeadLetterConfig = &eventbridge.DeadLetterConfig{
+			Arn: aws.String(r.deadLetterArn),
+		}
+	}
+
+	return t
+}
+
+// The longest statement ID Lambda accepts.
+const maxStatementId = 100
+
+var statementIdChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// ruleStatementId is the ID of the permission letting the rule with the given
+// ARN invoke the function. Rules on the default bus keep the ID used before
+// it was derived from the ARN.
+func ruleStatementId(ruleArn string) string {
+	// arn:...:rule/[<bus>/]<name>
+	name := ruleArn[strings.Index(ruleArn, ":rule/")+len(":rule/"):]
+
+	sid := "lambda-eventbridge-" + statementIdChars.ReplaceAllString(name, "-")
+	if len(sid) <= maxStatementId {
+		return sid
+	}
+
+	// Keep long IDs unique by ending them in a hash of the whole ARN
+	h := fnv.New32a()
+	h.Write([]byte(ruleArn))
+	suffix := fmt.Sprintf("-%08x", h.Sum32())
+
+	return sid[:maxStatementId-len(suffix)] + suffix
+}
+
+// ruleArn is the ARN of the rule in the account and region of the function.
+func ruleArn(funcArn, bus, name string) string {
+	// Buses may be given by ARN as well as by name
+	bus = bus[strings.LastIndex(bus, "/")+1:]
+
+	resource := "rule/" + name
+	if bus != "" && bus != "default" {
+		resource = "rule/" + bus + "/" + name
+	}
+
+	return fmt.Sprintf("arn:%s:events:%s:%s:%s", arnField(funcArn, 1), arnField(funcArn, 3), arnField(funcArn, 4), resource)
+}
+
+// grant lets the rule invoke arn unless the function's policy already does,
+// and revokes statements for the rule left under other IDs by earlier
+// releases.
+func (r *ruleRelease) grant(ctx context.Context, lamSvc *lambda.Lambda, arn string) error {
+	sid := ruleStatementId(r.arn)
+
+	statements, err := policyStatements(ctx, lamSvc, arn)
+	if err != nil {
+		return err
+	}
+
+	var granted bool
+	for _, s := range statements {
+		switch {
+		case s.Sid == sid:
+			granted = true
+		case s.sourceArn() == r.arn:
+			if err := removeStatement(ctx, lamSvc, arn, s.Sid); err != nil {
+				return err
+			}
+		}
+	}
+
+	if granted {
+		return nil
+	}
+
+	_, err = lamSvc.AddPermissionWithContext(ctx, &lambda.AddPermissionInput{
+		StatementId:  aws.String(sid),
+		FunctionName: aws.String(arn),
+		Action:       aws.String("lambda:InvokeFunction"),
+		Principal:    aws.String("events.amazonaws.com"),
+		SourceArn:    aws.String(r.arn),
+	})
+
+	// A concurrent release may have added it since the policy was read
+	if err != nil && !isCode(err, lambda.ErrCodeResourceConflictException) {
+		return errors.Wrapf(err, "unable to let rule %s invoke %s", r.Name, arn)
+	}
+
+	return nil
+}
+
+// currentTarget returns the ARN the app's target on the rule invokes, or an
+// empty string if there is no such target yet.
+func (r *RuleConfig) currentTarget(
+	ctx context.Context,
+	evSvc *eventbridge.EventBridge,
+	src *component.Source,
+) (string, error) {
+	out, err := evSvc.ListTargetsByRuleWithContext(ctx, &eventbridge.ListTargetsByRuleInput{
+		Rule:         aws.String(r.Name),
+		EventBusName: aws.String(r.EventBus),
+	})
+
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == eventbridge.ErrCodeResourceNotFoundException {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "unable to read EventBridge targets of rule %s", r.Name)
+	}
+
+	for _, t := range out.Targets {
+		if aws.StringValue(t.Id) == src.App {
+			return aws.StringValue(t.Arn), nil
+		}
+	}
+
+	return "", nil
+}
+
+// releasedRules returns the rules recorded in the release, or for releases
+// made before rules were recorded, the single rule named after the app.
+func (rm *ReleaseManager) releasedRules(src *component.Source, release *Release) []*Rule {
+	if len(release.Rules) > 0 {
+		return release.Rules
+	}
+
+	// Releases without rules consume other event sources
+	if len(release.EventSourceMappings) > 0 || len(release.BucketNotifications) > 0 ||
+		len(release.Subscriptions) > 0 || release.HttpApi != nil || release.Alb != nil {
+		return nil
+	}
+
+	bus := aws.StringValue(rm.config.EventBus)
+	if bus == "" {
+		bus = "default"
+	}
+
+	return []*Rule{{
+		Name:      src.App,
+		EventBus:  bus,
+		RuleArn:   ruleArn(release.FunctionArn, bus, src.App),
+		TargetArn: release.FunctionArn,
+	}}
+}
@@ -0,0 +1,55 @@
+package release
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuleStatementId(t *testing.T) {
+	const prefix = "arn:aws:events:eu-west-1:123456789012:rule/"
+
+	long := strings.Repeat("a", 90)
+
+	cases := []struct {
+		name string
+		arn  string
+		want string
+	}{
+		{"default bus", prefix + "orders", "lambda-eventbridge-orders"},
+		{"custom bus", prefix + "payments/orders", "lambda-eventbridge-payments-orders"},
+		{"invalid characters", prefix + "orders.created@v2", "lambda-eventbridge-orders-created-v2"},
+		{"longest", prefix + strings.Repeat("a", 81), "lambda-eventbridge-" + strings.Repeat("a", 81)},
+	}
+
+	for _, c := range cases {
+		if got := ruleStatementId(c.arn); got != c.want {
+			t.Errorf("%s: ruleStatementId(%q) = %q, want %q", c.name, c.arn, got, c.want)
+		}
+	}
+
+	// IDs over the limit are truncated and end in a hash of the whole ARN, so
+	// rules sharing a long prefix still get different IDs
+	a := ruleStatementId(prefix + long + "-first")
+	b := ruleStatementId(prefix + long + "-second")
+	c := ruleStatementId(prefix + "bus/" + long + "-first")
+
+	for _, id := range []string{a, b, c} {
+		if len(id) != maxStatementId {
+			t.Errorf("%q is %d characters, want %d", id, len(id), maxStatementId)
+		}
+		if !strings.HasPrefix(id, "lambda-eventbridge-") {
+			t.Errorf("%q doesn't start with lambda-eventbridge-", id)
+		}
+		if statementIdChars.MatchString(id) {
+			t.Errorf("%q holds characters Lambda doesn't accept", id)
+		}
+	}
+
+	if a == b || a == c || b == c {
+		t.Errorf("truncated IDs collide: %q, %q, %q", a, b, c)
+	}
+
+	if again := ruleStatementId(prefix + long + "-first"); again != a {
+		t.Errorf("ruleStatementId isn't stable: %q then %q", a, again)
+	}
+}
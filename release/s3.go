@@ -0,0 +1,251 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/pkg/errors"
+)
+
+type S3Config struct {
+	// Bucket is the name of the bucket whose object events invoke the
+	// function.
+	Bucket string `hcl:"bucket"`
+
+	// Events are the S3 event types, "s3:ObjectCreated:*" by default.
+	Events []string `hcl:"events,optional"`
+
+	// Prefix and Suffix limit the events to objects with matching keys.
+	Prefix string `hcl:"prefix,optional"`
+	Suffix string `hcl:"suffix,optional"`
+
+	// Account is the account owning the bucket, the function's by default.
+	Account string `hcl:"account,optional"`
+}
+
+// The event type used when none are given.
+const DefaultS3Event = "s3:ObjectCreated:*"
+
+var bucketName = regexp.MustCompile(`^[a-z0-9][a-z0-9.\-]{1,61}[a-z0-9]$`)
+
+func (c *S3Config) validate() error {
+	if !bucketName.MatchString(c.Bucket) {
+		return fmt.Errorf("s3 bucket %q is not a valid bucket name", c.Bucket)
+	}
+
+	for _, e := range c.Events {
+		if !strings.HasPrefix(e, "s3:") {
+			return fmt.Errorf("s3 event %q must start with s3:, such as %s", e, DefaultS3Event)
+		}
+	}
+
+	return nil
+}
+
+// statementId is the ID of the permission letting the bucket invoke the
+// function.
+func (c *S3Config) statementId() string {
+	return fmt.Sprintf("lambda-s3-%s", c.Bucket)
+}
+
+// bucketArn is the ARN of the bucket in the partition of the function.
+func bucketArn(funcArn, bucket string) string {
+	return fmt.Sprintf("arn:%s:s3:::%s", arnField(funcArn, 1), bucket)
+}
+
+// notificationId is the ID of the app's entry in a bucket's notification
+// configuration.
+func notificationId(src *component.Source) string {
+	return fmt.Sprintf("waypoint-%s", src.App)
+}
+
+// arnField returns a field of an ARN, such as 4 for the account.
+func arnField(arn string, i int) string {
+	parts := strings.Split(arn, ":")
+	if i >= len(parts) {
+		return ""
+	}
+	return parts[i]
+}
+
+// entry is the app's notification entry invoking arn.
+func (c *S3Config) entry(src *component.Source, arn string) *s3.LambdaFunctionConfiguration {
+	events := c.Events
+	if len(events) == 0 {
+		events = []string{DefaultS3Event}
+	}
+
+	entry := &s3.LambdaFunctionConfiguration{
+		Id:                aws.String(notificationId(src)),
+		LambdaFunctionArn: aws.String(arn),
+		Events:            aws.StringSlice(events),
+	}
+
+	var rules []*s3.FilterRule
+	if c.Prefix != "" {
+		rules = append(rules, &s3.FilterRule{Name: aws.String(s3.FilterRuleNamePrefix), Value: aws.String(c.Prefix)})
+	}
+	if c.Suffix != "" {
+		rules = append(rules, &s3.FilterRule{Name: aws.String(s3.FilterRuleNameSuffix), Value: aws.String(c.Suffix)})
+	}
+
+	if len(rules) > 0 {
+		entry.Filter = &s3.NotificationConfigurationFilter{
+			Key: &s3.KeyFilter{FilterRules: rules},
+		}
+	}
+
+	return entry
+}
+
+// bucketRelease is a bucket notification being released.
+type bucketRelease struct {
+	*S3Config
+
+	// The account owning the bucket.
+	account string
+
+	// The app's entry before the release, nil if the release added it.
+	previous *s3.LambdaFunctionConfiguration
+}
+
+// previousArn returns what the app's entry invoked before the release.
+func (b *bucketRelease) previousArn() string {
+	if b.previous == nil {
+		return ""
+	}
+	return aws.StringValue(b.previous.LambdaFunctionArn)
+}
+
+// subscribeBucket lets the bucket invoke arn and points the app's entry in
+// its notification configuration at it.
+func (rm *ReleaseManager) subscribeBucket(
+	ctx context.Context,
+	lamSvc *lambda.Lambda,
+	s3Svc *s3.S3,
+	src *component.Source,
+	c *S3Config,
+	arn string,
+) (*bucketRelease, error) {
+	b := &bucketRelease{
+		S3Config: c,
+		account:  c.Account,
+	}
+
+	if b.account == "" {
+		b.account = arnField(arn, 4)
+	}
+
+	// S3 checks that it may invoke the function when the entry is added
+	_, err := lamSvc.AddPermissionWithContext(ctx, &lambda.AddPermissionInput{
+		StatementId:   aws.String(c.statementId()),
+		FunctionName:  aws.String(arn),
+		Action:        aws.String("lambda:InvokeFunction"),
+		Principal:     aws.String("s3.amazonaws.com"),
+		SourceArn:     aws.String(bucketArn(arn, c.Bucket)),
+		SourceAccount: aws.String(b.account),
+	})
+
+	if err != nil && !isCode(err, lambda.ErrCodeResourceConflictException) {
+		return nil, errors.Wrapf(err, "unable to let S3 bucket %s invoke the function", c.Bucket)
+	}
+
+	b.previous, err = putEntry(ctx, s3Svc, c.Bucket, b.account, notificationId(src), c.entry(src, arn))
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// restore puts the app's entry back the way it was before the release.
+func (b *bucketRelease) restore(ctx context.Context, s3Svc *s3.S3, src *component.Source) error {
+	_, err := putEntry(ctx, s3Svc, b.Bucket, b.account, notificationId(src), b.previous)
+	return err
+}
+
+// putEntry replaces the entry with the given ID in the bucket's notification
+// configuration, or removes it if entry is nil, leaving every other entry and
+// setting as it is. It returns the entry that was replaced, if any.
+func putEntry(
+	ctx context.Context,
+	s3Svc *s3.S3,
+	bucket string,
+	account string,
+	id string,
+	entry *s3.LambdaFunctionConfiguration,
+) (*s3.LambdaFunctionConfiguration, error) {
+	cfg, err := s3Svc.GetBucketNotificationConfigurationWithContext(ctx, &s3.GetBucketNotificationConfigurationRequest{
+		Bucket:              aws.String(bucket),
+		ExpectedBucketOwner: aws.String(account),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read the notification configuration of S3 bucket %s", bucket)
+	}
+
+	var previous *s3.LambdaFunctionConfiguration
+	var entries []*s3.LambdaFunctionConfiguration
+	for _, e := range cfg.LambdaFunctionConfigurations {
+		if aws.StringValue(e.Id) == id {
+			previous = e
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	if previous == nil && entry == nil {
+		return nil, nil
+	}
+
+	if entry != nil {
+		entries = append(entries, entry)
+	}
+
+	// The rest of the configuration, including EventBridgeConfiguration, is
+	// written back as it was read, so that delivery to EventBridge and other
+	// destinations stays on
+	cfg.LambdaFunctionConfigurations = entries
+
+	_, err = s3Svc.PutBucketNotificationConfigurationWithContext(ctx, &s3.PutBucketNotificationConfigurationInput{
+		Bucket:                    aws.String(bucket),
+		ExpectedBucketOwner:       aws.String(account),
+		NotificationConfiguration: cfg,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to update the notification configuration of S3 bucket %s", bucket)
+	}
+
+	return previous, nil
+}
+
+// findEntry returns the entry with the given ID in the bucket's notification
+// configuration, or nil if there is none.
+func findEntry(
+	ctx context.Context,
+	s3Svc *s3.S3,
+	bucket string,
+	account string,
+	id string,
+) (*s3.LambdaFunctionConfiguration, error) {
+	cfg, err := s3Svc.GetBucketNotificationConfigurationWithContext(ctx, &s3.GetBucketNotificationConfigurationRequest{
+		Bucket:              aws.String(bucket),
+		ExpectedBucketOwner: aws.String(account),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read the notification configuration of S3 bucket %s", bucket)
+	}
+
+	for _, e := range cfg.LambdaFunctionConfigurations {
+		if aws.StringValue(e.Id) == id {
+			return e, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,285 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/pkg/errors"
+)
+
+type SNSConfig struct {
+	// TopicArn is the topic the function subscribes to.
+	TopicArn string `hcl:"topic_arn"`
+
+	// FilterPolicy is a JSON filter policy limiting the messages delivered
+	// to the function.
+	FilterPolicy string `hcl:"filter_policy,optional"`
+
+	// FilterPolicyScope is what the filter policy matches, MessageAttributes
+	// (the default) or MessageBody.
+	FilterPolicyScope string `hcl:"filter_policy_scope,optional"`
+
+	// DeadLetterQueueArn is an SQS queue receiving the messages that
+	// couldn't be delivered to the function.
+	DeadLetterQueueArn string `hcl:"dead_letter_queue_arn,optional"`
+}
+
+// The values SNS accepts for a filter policy scope.
+const (
+	FilterPolicyScopeAttributes = "MessageAttributes"
+	FilterPolicyScopeBody       = "MessageBody"
+)
+
+func (c *SNSConfig) validate() error {
+	if !strings.HasPrefix(c.TopicArn, "arn:") || !strings.Contains(c.TopicArn, ":sns:") {
+		return fmt.Errorf("sns topic_arn %q is not an SNS topic ARN", c.TopicArn)
+	}
+
+	if c.FilterPolicy != "" {
+		var policy map[string]interface{}
+		if err := json.Unmarshal([]byte(c.FilterPolicy), &policy); err != nil {
+			return fmt.Errorf("sns filter_policy of %s is not a JSON object: %s", c.TopicArn, err)
+		}
+	}
+
+	switch c.FilterPolicyScope {
+	case "":
+	case FilterPolicyScopeAttributes, FilterPolicyScopeBody:
+		if c.FilterPolicy == "" {
+			return fmt.Errorf("sns filter_policy_scope requires filter_policy")
+		}
+	default:
+		return fmt.Errorf("sns filter_policy_scope must be %s or %s", FilterPolicyScopeAttributes, FilterPolicyScopeBody)
+	}
+
+	if d := c.DeadLetterQueueArn; d != "" && !strings.Contains(d, ":sqs:") {
+		return fmt.Errorf("sns dead_letter_queue_arn %q is not an SQS queue ARN", d)
+	}
+
+	return nil
+}
+
+// statementId is the ID of the permission letting the topic invoke the
+// function.
+func (c *SNSConfig) statementId() string {
+	return fmt.Sprintf("lambda-sns-%s", arnField(c.TopicArn, 5))
+}
+
+// attributes returns the subscription attributes the config asks for, in the
+// order they must be set, with empty values for those it leaves unset.
+func (c *SNSConfig) attributes() [][2]string {
+	scope := c.FilterPolicyScope
+	if scope == "" && c.FilterPolicy != "" {
+		scope = FilterPolicyScopeAttributes
+	}
+
+	var redrive string
+	if c.DeadLetterQueueArn != "" {
+		data, _ := json.Marshal(map[string]string{"deadLetterTargetArn": c.DeadLetterQueueArn})
+		redrive = string(data)
+	}
+
+	// The scope decides how the policy is parsed, so it goes first
+	return [][2]string{
+		{"FilterPolicyScope", scope},
+		{"FilterPolicy", c.FilterPolicy},
+		{"RedrivePolicy", redrive},
+	}
+}
+
+// subscriptionRelease is a topic subscription being released.
+type subscriptionRelease struct {
+	*SNSConfig
+
+	arn      string
+	endpoint string
+
+	// What the app's subscription delivered to before the release, empty if
+	// the release created it.
+	previousArn string
+
+	// The endpoints of the subscriptions the release replaced.
+	replaced []string
+}
+
+// subscribeTopic lets the topic invoke arn and moves the function's
+// subscription to it.
+func (rm *ReleaseManager) subscribeTopic(
+	ctx context.Context,
+	lamSvc *lambda.Lambda,
+	snsSvc *sns.SNS,
+	c *SNSConfig,
+	arn string,
+) (*subscriptionRelease, error) {
+	_, err := lamSvc.AddPermissionWithContext(ctx, &lambda.AddPermissionInput{
+		StatementId:  aws.String(c.statementId()),
+		FunctionName: aws.String(arn),
+		Action:       aws.String("lambda:InvokeFunction"),
+		Principal:    aws.String("sns.amazonaws.com"),
+		SourceArn:    aws.String(c.TopicArn),
+	})
+
+	if err != nil && !isCode(err, lambda.ErrCodeResourceConflictException) {
+		return nil, errors.Wrapf(err, "unable to let SNS topic %s invoke the function", c.TopicArn)
+	}
+
+	subs, err := findSubscriptions(ctx, snsSvc, c.TopicArn, arn)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &subscriptionRelease{
+		SNSConfig: c,
+		endpoint:  arn,
+	}
+
+	// Subscriptions can't change their endpoint, so the one to the previous
+	// qualifier is replaced
+	var stale []*sns.Subscription
+	for _, sub := range subs {
+		if aws.StringValue(sub.Endpoint) == arn {
+			s.arn = aws.StringValue(sub.SubscriptionArn)
+			s.previousArn = arn
+		} else {
+			stale = append(stale, sub)
+		}
+	}
+
+	if s.arn != "" {
+		err = configure(ctx, snsSvc, s.arn, c)
+	} else {
+		s.arn, err = subscribe(ctx, snsSvc, c, arn)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sub := range stale {
+		_, err = snsSvc.UnsubscribeWithContext(ctx, &sns.UnsubscribeInput{
+			SubscriptionArn: sub.SubscriptionArn,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to unsubscribe %s from SNS topic %s", aws.StringValue(sub.Endpoint), c.TopicArn)
+		}
+
+		if s.previousArn == "" {
+			s.previousArn = aws.StringValue(sub.Endpoint)
+		}
+		s.replaced = append(s.replaced, aws.StringValue(sub.Endpoint))
+	}
+
+	return s, nil
+}
+
+// restore moves the subscription back to what it delivered to before the
+// release, or removes it if the release created it.
+func (s *subscriptionRelease) restore(ctx context.Context, snsSvc *sns.SNS) error {
+	if s.previousArn == s.endpoint {
+		return nil
+	}
+
+	if s.previousArn != "" {
+		if _, err := subscribe(ctx, snsSvc, s.SNSConfig, s.previousArn); err != nil {
+			return err
+		}
+	}
+
+	_, err := snsSvc.UnsubscribeWithContext(ctx, &sns.UnsubscribeInput{
+		SubscriptionArn: aws.String(s.arn),
+	})
+	return err
+}
+
+// findSubscriptions returns the topic's subscriptions delivering to any
+// version or alias of the function.
+func findSubscriptions(
+	ctx context.Context,
+	snsSvc *sns.SNS,
+	topicArn string,
+	funcArn string,
+) ([]*sns.Subscription, error) {
+	var found []*sns.Subscription
+
+	err := snsSvc.ListSubscriptionsByTopicPagesWithContext(ctx, &sns.ListSubscriptionsByTopicInput{
+		TopicArn: aws.String(topicArn),
+	}, func(out *sns.ListSubscriptionsByTopicOutput, last bool) bool {
+		for _, sub := range out.Subscriptions {
+			if aws.StringValue(sub.Protocol) == "lambda" && unqualified(aws.StringValue(sub.Endpoint)) == unqualified(funcArn) {
+				found = append(found, sub)
+			}
+		}
+		return true
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list subscriptions of SNS topic %s", topicArn)
+	}
+
+	return found, nil
+}
+
+// subscribe subscribes arn to the topic with the configured attributes and
+// returns the subscription's ARN.
+func subscribe(ctx context.Context, snsSvc *sns.SNS, c *SNSConfig, arn string) (string, error) {
+	attrs := map[string]*string{}
+	for _, a := range c.attributes() {
+		if a[1] != "" {
+			attrs[a[0]] = aws.String(a[1])
+		}
+	}
+
+	out, err := snsSvc.SubscribeWithContext(ctx, &sns.SubscribeInput{
+		TopicArn:              aws.String(c.TopicArn),
+		Protocol:              aws.String("lambda"),
+		Endpoint:              aws.String(arn),
+		Attributes:            attrs,
+		ReturnSubscriptionArn: aws.Bool(true),
+	})
+
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to subscribe %s to SNS topic %s", arn, c.TopicArn)
+	}
+
+	return aws.StringValue(out.SubscriptionArn), nil
+}
+
+// configure brings the attributes of an existing subscription in line with
+// the config, clearing those it leaves unset.
+func configure(ctx context.Context, snsSvc *sns.SNS, subArn string, c *SNSConfig) error {
+	out, err := snsSvc.GetSubscriptionAttributesWithContext(ctx, &sns.GetSubscriptionAttributesInput{
+		SubscriptionArn: aws.String(subArn),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to read subscription %s", subArn)
+	}
+
+	for _, a := range c.attributes() {
+		name, value := a[0], a[1]
+
+		current := aws.StringValue(out.Attributes[name])
+		if value == current || (name == "FilterPolicyScope" && value == "") {
+			continue
+		}
+
+		// An empty policy removes the filter
+		if name == "FilterPolicy" && value == "" {
+			value = "{}"
+		}
+
+		_, err = snsSvc.SetSubscriptionAttributesWithContext(ctx, &sns.SetSubscriptionAttributesInput{
+			SubscriptionArn: aws.String(subArn),
+			AttributeName:   aws.String(name),
+			AttributeValue:  aws.String(value),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "unable to set %s of subscription %s", name, subArn)
+		}
+	}
+
+	return nil
+}
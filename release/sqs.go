@@ -0,0 +1,124 @@
+package release
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+type SQSConfig struct {
+	// QueueArn is the queue the function consumes.
+	QueueArn string `hcl:"queue_arn"`
+
+	// BatchSize is the most messages passed to one invocation, 10 by
+	// default.
+	BatchSize *int64 `hcl:"batch_size,optional"`
+
+	// BatchingWindow is how long messages are gathered before the function
+	// is invoked, such as "30s".
+	BatchingWindow string `hcl:"batching_window,optional"`
+
+	// ReportBatchItemFailures lets the function return the messages it
+	// failed to process so that only those are retried.
+	ReportBatchItemFailures bool `hcl:"report_batch_item_failures,optional"`
+
+	// Enabled is whether the queue is polled, true by default.
+	Enabled *bool `hcl:"enabled,optional"`
+
+	// MaximumConcurrency caps how many concurrent invocations the queue may
+	// drive, unlimited by default.
+	MaximumConcurrency *int64 `hcl:"maximum_concurrency,optional"`
+}
+
+// The bounds Lambda puts on polling SQS queues.
+const (
+	maxQueueBatchSize     = 10000
+	maxFifoQueueBatchSize = 10
+	maxBatchingWindow     = 5 * time.Minute
+	minQueueConcurrency   = 2
+	maxQueueConcurrency   = 1000
+)
+
+func (c *SQSConfig) kind() string {
+	return "SQS queue"
+}
+
+func (c *SQSConfig) validate() error {
+	if !strings.HasPrefix(c.QueueArn, "arn:") || !strings.Contains(c.QueueArn, ":sqs:") {
+		return fmt.Errorf("sqs queue_arn %q is not an SQS queue ARN", c.QueueArn)
+	}
+
+	max := int64(maxQueueBatchSize)
+	if strings.HasSuffix(c.QueueArn, ".fifo") {
+		max = maxFifoQueueBatchSize
+	}
+
+	if c.BatchSize != nil && (*c.BatchSize < 1 || *c.BatchSize > max) {
+		return fmt.Errorf("sqs batch_size must be between 1 and %d", max)
+	}
+
+	var window time.Duration
+	if c.BatchingWindow != "" {
+		var err error
+		window, err = time.ParseDuration(c.BatchingWindow)
+		if err != nil {
+			return fmt.Errorf("invalid sqs batching_window %q: %s", c.BatchingWindow, err)
+		}
+
+		if window < 0 || window > maxBatchingWindow {
+			return fmt.Errorf("sqs batching_window must be between 0s and %s", maxBatchingWindow)
+		}
+	}
+
+	if m := c.MaximumConcurrency; m != nil && (*m < minQueueConcurrency || *m > maxQueueConcurrency) {
+		return fmt.Errorf("sqs maximum_concurrency must be between %d and %d", minQueueConcurrency, maxQueueConcurrency)
+	}
+
+	// Batches of more than 10 messages need time to be gathered
+	if c.BatchSize != nil && *c.BatchSize > 10 && window < time.Second {
+		return fmt.Errorf("sqs batch_size over 10 requires a batching_window of at least 1s")
+	}
+
+	return nil
+}
+
+// mappingInputs returns the requests creating or updating the mapping from
+// the queue to arn.
+func (c *SQSConfig) mappingInputs(arn string) (*lambda.CreateEventSourceMappingInput, *lambda.UpdateEventSourceMappingInput) {
+	enabled := true
+	if c.Enabled != nil {
+		enabled = *c.Enabled
+	}
+
+	// An empty list turns partial batch responses off on update
+	responseTypes := []*string{}
+	if c.ReportBatchItemFailures {
+		responseTypes = aws.StringSlice([]string{lambda.FunctionResponseTypeReportBatchItemFailures})
+	}
+
+	// An empty scaling config removes the cap on update
+	scaling := &lambda.ScalingConfig{MaximumConcurrency: c.MaximumConcurrency}
+
+	create := &lambda.CreateEventSourceMappingInput{
+		EventSourceArn:                 aws.String(c.QueueArn),
+		FunctionName:                   aws.String(arn),
+		BatchSize:                      c.BatchSize,
+		MaximumBatchingWindowInSeconds: seconds(c.BatchingWindow),
+		FunctionResponseTypes:          responseTypes,
+		ScalingConfig:                  scaling,
+		Enabled:                        aws.Bool(enabled),
+	}
+
+	update := &lambda.UpdateEventSourceMappingInput{
+		BatchSize:                      c.BatchSize,
+		MaximumBatchingWindowInSeconds: seconds(c.BatchingWindow),
+		FunctionResponseTypes:          responseTypes,
+		ScalingConfig:                  scaling,
+		Enabled:                        aws.Bool(enabled),
+	}
+
+	return create, update
+}
@@ -0,0 +1,50 @@
+package release
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestSQSValidate(t *testing.T) {
+	const queue = "arn:aws:sqs:eu-west-1:123456789012:orders"
+	const fifo = "arn:aws:sqs:eu-west-1:123456789012:orders.fifo"
+
+	cases := []struct {
+		name   string
+		config SQSConfig
+		valid  bool
+	}{
+		{"queue", SQSConfig{QueueArn: queue}, true},
+		{"batch size", SQSConfig{QueueArn: queue, BatchSize: aws.Int64(10)}, true},
+		{"large batch with window", SQSConfig{QueueArn: queue, BatchSize: aws.Int64(10000), BatchingWindow: "1s"}, true},
+		{"fifo batch size", SQSConfig{QueueArn: fifo, BatchSize: aws.Int64(10)}, true},
+		{"longest window", SQSConfig{QueueArn: queue, BatchingWindow: "5m"}, true},
+		{"concurrency bounds", SQSConfig{QueueArn: queue, MaximumConcurrency: aws.Int64(2)}, true},
+		{"concurrency upper bound", SQSConfig{QueueArn: queue, MaximumConcurrency: aws.Int64(1000)}, true},
+
+		{"not an arn", SQSConfig{QueueArn: "orders"}, false},
+		{"not a queue", SQSConfig{QueueArn: "arn:aws:sns:eu-west-1:123456789012:orders"}, false},
+		{"zero batch size", SQSConfig{QueueArn: queue, BatchSize: aws.Int64(0)}, false},
+		{"batch size too large", SQSConfig{QueueArn: queue, BatchSize: aws.Int64(10001), BatchingWindow: "1s"}, false},
+		{"fifo batch size too large", SQSConfig{QueueArn: fifo, BatchSize: aws.Int64(11), BatchingWindow: "1s"}, false},
+		{"large batch without window", SQSConfig{QueueArn: queue, BatchSize: aws.Int64(11)}, false},
+		{"invalid window", SQSConfig{QueueArn: queue, BatchingWindow: "soon"}, false},
+		{"window too long", SQSConfig{QueueArn: queue, BatchingWindow: "6m"}, false},
+		{"negative window", SQSConfig{QueueArn: queue, BatchingWindow: "-1s"}, false},
+		{"concurrency too low", SQSConfig{QueueArn: queue, MaximumConcurrency: aws.Int64(1)}, false},
+		{"concurrency too high", SQSConfig{QueueArn: queue, MaximumConcurrency: aws.Int64(1001)}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.config.validate()
+			if c.valid && err != nil {
+				t.Errorf("validate: %s", err)
+			}
+			if !c.valid && err == nil {
+				t.Errorf("validate succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,438 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/apigatewayv2"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	sdk "github.com/hashicorp/waypoint-plugin-sdk/proto/gen"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/hashicorp/waypoint/builtin/aws/utils"
+	"github.com/pkg/errors"
+)
+
+// The window of CloudWatch metrics a status report covers.
+const statusWindow = 15 * time.Minute
+
+// Implement Status
+func (rm *ReleaseManager) StatusFunc() interface{} {
+	return rm.status
+}
+
+func (rm *ReleaseManager) status(
+	ctx context.Context,
+	log hclog.Logger,
+	src *component.Source,
+	release *Release,
+	ui terminal.UI,
+) (*sdk.StatusReport, error) {
+	sg := ui.StepGroup()
+	defer sg.Wait()
+
+	step := sg.Add("Checking status of release")
+	defer step.Abort()
+
+	sess, err := utils.GetSession(&utils.SessionConfig{
+		Region: rm.config.Region,
+		Logger: log,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &sdk.StatusReport{
+		External: true,
+		Health:   sdk.StatusReport_READY,
+	}
+
+	evSvc := eventbridge.New(sess)
+	cwSvc := cloudwatch.New(sess)
+	lamSvc := lambda.New(sess)
+
+	// The release is as healthy as its least healthy rule
+	rank := map[sdk.StatusReport_Health]int{
+		sdk.StatusReport_READY:   0,
+		sdk.StatusReport_PARTIAL: 1,
+		sdk.StatusReport_DOWN:    2,
+	}
+
+	var messages []string
+	for _, r := range rm.releasedRules(src, release) {
+		health, details, err := ruleStatus(ctx, evSvc, cwSvc, src, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if rank[health] > rank[report.Health] {
+			report.Health = health
+		}
+
+		messages = append(messages, fmt.Sprintf("rule %s: %s", r.Name, strings.Join(details, ", ")))
+	}
+
+	for _, m := range release.EventSourceMappings {
+		health, details, err := mappingStatus(ctx, lamSvc, m)
+		if err != nil {
+			return nil, err
+		}
+
+		if rank[health] > rank[report.Health] {
+			report.Health = health
+		}
+
+		messages = append(messages, fmt.Sprintf("mapping of %s: %s", m.EventSourceArn, strings.Join(details, ", ")))
+	}
+
+	s3Svc := s3.New(sess)
+	for _, b := range release.BucketNotifications {
+		health, details, err := bucketStatus(ctx, s3Svc, b)
+		if err != nil {
+			return nil, err
+		}
+
+		if rank[health] > rank[report.Health] {
+			report.Health = health
+		}
+
+		messages = append(messages, fmt.Sprintf("s3 bucket %s: %s", b.Bucket, strings.Join(details, ", ")))
+	}
+
+	snsSvc := sns.New(sess)
+	for _, s := range release.Subscriptions {
+		health, details, err := subscriptionStatus(ctx, snsSvc, s)
+		if err != nil {
+			return nil, err
+		}
+
+		if rank[health] > rank[report.Health] {
+			report.Health = health
+		}
+
+		messages = append(messages, fmt.Sprintf("sns topic %s: %s", s.TopicArn, strings.Join(details, ", ")))
+	}
+
+	if api := release.HttpApi; api != nil {
+		health, details, err := apiStatus(ctx, apigatewayv2.New(sess), api)
+		if err != nil {
+			return nil, err
+		}
+
+		if rank[health] > rank[report.Health] {
+			report.Health = health
+		}
+
+		if release.Url != "" {
+			details = append(details, fmt.Sprintf("serving %s", release.Url))
+		}
+
+		messages = append(messages, fmt.Sprintf("http api %s: %s", api.ApiId, strings.Join(details, ", ")))
+	}
+
+	if alb := release.Alb; alb != nil {
+		health, details, err := targetStatus(ctx, elbv2.New(sess), alb)
+		if err != nil {
+			return nil, err
+		}
+
+		if rank[health] > rank[report.Health] {
+			report.Health = health
+		}
+
+		messages = append(messages, fmt.Sprintf("target group %s: %s", alb.TargetGroupArn, strings.Join(details, ", ")))
+	}
+
+	report.HealthMessage = strings.Join(messages, "; ")
+
+	step.Update("Release is %s", strings.ToLower(report.Health.String()))
+	step.Done()
+
+	return report, nil
+}
+
+// ruleStatus checks that the rule is enabled, targets what the release
+// recorded and is delivering events.
+func ruleStatus(
+	ctx context.Context,
+	evSvc *eventbridge.EventBridge,
+	cwSvc *cloudwatch.CloudWatch,
+	src *component.Source,
+	r *Rule,
+) (sdk.StatusReport_Health, []string, error) {
+	rule, err := evSvc.DescribeRuleWithContext(ctx, &eventbridge.DescribeRuleInput{
+		Name:         aws.String(r.Name),
+		EventBusName: aws.String(r.EventBus),
+	})
+
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == eventbridge.ErrCodeResourceNotFoundException {
+			return sdk.StatusReport_DOWN, []string{"does not exist"}, nil
+		}
+		return sdk.StatusReport_UNKNOWN, nil, errors.Wrapf(err, "unable to read EventBridge rule %s", r.Name)
+	}
+
+	targets, err := evSvc.ListTargetsByRuleWithContext(ctx, &eventbridge.ListTargetsByRuleInput{
+		Rule:         aws.String(r.Name),
+		EventBusName: aws.String(r.EventBus),
+	})
+	if err != nil {
+		return sdk.StatusReport_UNKNOWN, nil, errors.Wrapf(err, "unable to read EventBridge targets")
+	}
+
+	var targetArn string
+	for _, t := range targets.Targets {
+		if aws.StringValue(t.Id) == src.App {
+			targetArn = aws.StringValue(t.Arn)
+		}
+	}
+
+	// Rules on custom buses report their metrics per bus as well
+	dimensions := []*cloudwatch.Dimension{
+		{Name: aws.String("RuleName"), Value: aws.String(r.Name)},
+	}
+	if r.EventBus != "default" {
+		dimensions = append(dimensions, &cloudwatch.Dimension{
+			Name: aws.String("EventBusName"), Value: aws.String(r.EventBus),
+		})
+	}
+
+	failed, err := failedInvocations(ctx, cwSvc, dimensions)
+	if err != nil {
+		return sdk.StatusReport_UNKNOWN, nil, err
+	}
+
+	state := aws.StringValue(rule.State)
+
+	details := []string{
+		fmt.Sprintf("state %s", state),
+		fmt.Sprintf("%.0f failed invocations in the last %s", failed, statusWindow),
+	}
+
+	switch {
+	case state != eventbridge.RuleStateEnabled:
+		return sdk.StatusReport_DOWN, details, nil
+	case targetArn != r.TargetArn:
+		details = append(details, fmt.Sprintf("target invokes %q instead of %s", targetArn, r.TargetArn))
+		return sdk.StatusReport_DOWN, details, nil
+	case failed > 0:
+		// Degraded: events are matched but not all are delivered
+		return sdk.StatusReport_PARTIAL, details, nil
+	}
+
+	details = append(details, fmt.Sprintf("target invokes %s", targetArn))
+
+	return sdk.StatusReport_READY, details, nil
+}
+
+// mappingStatus checks that the event source mapping is in the state the
+// release left it in and invokes what the release recorded.
+func mappingStatus(
+	ctx context.Context,
+	lamSvc *lambda.Lambda,
+	m *EventSourceMapping,
+) (sdk.StatusReport_Health, []string, error) {
+	out, err := lamSvc.GetEventSourceMappingWithContext(ctx, &lambda.GetEventSourceMappingInput{
+		UUID: aws.String(m.Uuid),
+	})
+
+	if err != nil {
+		if isCode(err, lambda.ErrCodeResourceNotFoundException) {
+			return sdk.StatusReport_DOWN, []string{"does not exist"}, nil
+		}
+		return sdk.StatusReport_UNKNOWN, nil, errors.Wrapf(err, "unable to read event source mapping %s", m.Uuid)
+	}
+
+	state := aws.StringValue(out.State)
+	functionArn := aws.StringValue(out.FunctionArn)
+
+	details := []string{fmt.Sprintf("state %s", state)}
+	if result := aws.StringValue(out.LastProcessingResult); result != "" {
+		details = append(details, fmt.Sprintf("last processing result %s", result))
+	}
+
+	expected := "Disabled"
+	if m.Enabled {
+		expected = "Enabled"
+	}
+
+	switch {
+	case functionArn != m.FunctionArn:
+		details = append(details, fmt.Sprintf("invokes %s instead of %s", functionArn, m.FunctionArn))
+		return sdk.StatusReport_DOWN, details, nil
+	case state == "Disabled" && m.Enabled:
+		return sdk.StatusReport_DOWN, details, nil
+	case state != expected:
+		// Still creating, updating or switching on or off
+		return sdk.StatusReport_PARTIAL, details, nil
+	}
+
+	details = append(details, fmt.Sprintf("invokes %s", functionArn))
+
+	return sdk.StatusReport_READY, details, nil
+}
+
+// bucketStatus checks that the app's entry is still in the bucket's
+// notification configuration and invokes what the release recorded.
+func bucketStatus(
+	ctx context.Context,
+	s3Svc *s3.S3,
+	b *BucketNotification,
+) (sdk.StatusReport_Health, []string, error) {
+	entry, err := findEntry(ctx, s3Svc, b.Bucket, b.Account, b.Id)
+	if err != nil {
+		return sdk.StatusReport_UNKNOWN, nil, err
+	}
+
+	if entry == nil {
+		return sdk.StatusReport_DOWN, []string{"notification removed"}, nil
+	}
+
+	functionArn := aws.StringValue(entry.LambdaFunctionArn)
+	if functionArn != b.FunctionArn {
+		return sdk.StatusReport_DOWN, []string{fmt.Sprintf("invokes %s instead of %s", functionArn, b.FunctionArn)}, nil
+	}
+
+	details := []string{
+		fmt.Sprintf("events %s", strings.Join(aws.StringValueSlice(entry.Events), ", ")),
+		fmt.Sprintf("invokes %s", functionArn),
+	}
+
+	return sdk.StatusReport_READY, details, nil
+}
+
+// subscriptionStatus checks that the subscription still exists, is confirmed
+// and delivers to what the release recorded.
+func subscriptionStatus(
+	ctx context.Context,
+	snsSvc *sns.SNS,
+	s *Subscription,
+) (sdk.StatusReport_Health, []string, error) {
+	out, err := snsSvc.GetSubscriptionAttributesWithContext(ctx, &sns.GetSubscriptionAttributesInput{
+		SubscriptionArn: aws.String(s.SubscriptionArn),
+	})
+
+	if err != nil {
+		if isCode(err, sns.ErrCodeNotFoundException) {
+			return sdk.StatusReport_DOWN, []string{"does not exist"}, nil
+		}
+		return sdk.StatusReport_UNKNOWN, nil, errors.Wrapf(err, "unable to read subscription %s", s.SubscriptionArn)
+	}
+
+	endpoint := aws.StringValue(out.Attributes["Endpoint"])
+
+	switch {
+	case endpoint != s.FunctionArn:
+		return sdk.StatusReport_DOWN, []string{fmt.Sprintf("delivers to %s instead of %s", endpoint, s.FunctionArn)}, nil
+	case aws.StringValue(out.Attributes["PendingConfirmation"]) == "true":
+		return sdk.StatusReport_PARTIAL, []string{"pending confirmation"}, nil
+	}
+
+	return sdk.StatusReport_READY, []string{fmt.Sprintf("delivers to %s", endpoint)}, nil
+}
+
+// apiStatus checks that the API's integration still exists and invokes what
+// the release recorded.
+func apiStatus(
+	ctx context.Context,
+	apiSvc *apigatewayv2.ApiGatewayV2,
+	api *HttpApi,
+) (sdk.StatusReport_Health, []string, error) {
+	out, err := apiSvc.GetIntegrationWithContext(ctx, &apigatewayv2.GetIntegrationInput{
+		ApiId:         aws.String(api.ApiId),
+		IntegrationId: aws.String(api.IntegrationId),
+	})
+
+	if err != nil {
+		if isCode(err, apigatewayv2.ErrCodeNotFoundException) {
+			return sdk.StatusReport_DOWN, []string{"does not exist"}, nil
+		}
+		return sdk.StatusReport_UNKNOWN, nil, errors.Wrapf(err, "unable to read integration of API %s", api.ApiId)
+	}
+
+	uri := aws.StringValue(out.IntegrationUri)
+	if uri != api.FunctionArn {
+		return sdk.StatusReport_DOWN, []string{fmt.Sprintf("invokes %s instead of %s", uri, api.FunctionArn)}, nil
+	}
+
+	return sdk.StatusReport_READY, []string{fmt.Sprintf("invokes %s", uri)}, nil
+}
+
+// targetStatus checks that the target group still invokes what the release
+// registered and reports the target's health.
+func targetStatus(
+	ctx context.Context,
+	elbSvc *elbv2.ELBV2,
+	alb *AlbTarget,
+) (sdk.StatusReport_Health, []string, error) {
+	out, err := elbSvc.DescribeTargetHealthWithContext(ctx, &elbv2.DescribeTargetHealthInput{
+		TargetGroupArn: aws.String(alb.TargetGroupArn),
+	})
+
+	if err != nil {
+		if isCode(err, elbv2.ErrCodeTargetGroupNotFoundException) {
+			return sdk.StatusReport_DOWN, []string{"does not exist"}, nil
+		}
+		return sdk.StatusReport_UNKNOWN, nil, errors.Wrapf(err, "unable to read targets of %s", alb.TargetGroupArn)
+	}
+
+	for _, t := range out.TargetHealthDescriptions {
+		id := aws.StringValue(t.Target.Id)
+		if id != alb.FunctionArn {
+			return sdk.StatusReport_DOWN, []string{fmt.Sprintf("invokes %s instead of %s", id, alb.FunctionArn)}, nil
+		}
+
+		// Lambda targets are unavailable until health checks are turned on
+		state := aws.StringValue(t.TargetHealth.State)
+		switch state {
+		case elbv2.TargetHealthStateEnumHealthy, elbv2.TargetHealthStateEnumUnavailable:
+			return sdk.StatusReport_READY, []string{fmt.Sprintf("invokes %s", id)}, nil
+		case elbv2.TargetHealthStateEnumUnhealthy:
+			return sdk.StatusReport_DOWN, []string{fmt.Sprintf("%s is unhealthy: %s", id, aws.StringValue(t.TargetHealth.Description))}, nil
+		default:
+			return sdk.StatusReport_PARTIAL, []string{fmt.Sprintf("%s is %s", id, state)}, nil
+		}
+	}
+
+	return sdk.StatusReport_DOWN, []string{"has no targets"}, nil
+}
+
+// failedInvocations sums the rule's FailedInvocations over the status window.
+func failedInvocations(
+	ctx context.Context,
+	cwSvc *cloudwatch.CloudWatch,
+	dimensions []*cloudwatch.Dimension,
+) (float64, error) {
+	now := time.Now()
+
+	out, err := cwSvc.GetMetricStatisticsWithContext(ctx, &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String("AWS/Events"),
+		MetricName: aws.String("FailedInvocations"),
+		Dimensions: dimensions,
+		StartTime:  aws.Time(now.Add(-statusWindow)),
+		EndTime:    aws.Time(now),
+		Period:     aws.Int64(int64(statusWindow / time.Second)),
+		Statistics: []*string{aws.String(cloudwatch.StatisticSum)},
+	})
+
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to read FailedInvocations metric")
+	}
+
+	var sum float64
+	for _, dp := range out.Datapoints {
+		sum += aws.Float64Value(dp.Sum)
+	}
+
+	return sum, nil
+}
@@ -0,0 +1,232 @@
+package release
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+type StreamConfig struct {
+	// SourceArn is the Kinesis stream or DynamoDB stream the function
+	// consumes.
+	SourceArn string `hcl:"source_arn"`
+
+	// StartingPosition is where a new mapping starts reading: TRIM_HORIZON,
+	// LATEST (the default) or, for Kinesis, AT_TIMESTAMP with
+	// starting_timestamp set to an RFC 3339 time.
+	StartingPosition  string `hcl:"starting_position,optional"`
+	StartingTimestamp string `hcl:"starting_timestamp,optional"`
+
+	// BatchSize is the most records passed to one invocation, 100 by
+	// default, and BatchingWindow how long records are gathered for.
+	BatchSize      *int64 `hcl:"batch_size,optional"`
+	BatchingWindow string `hcl:"batching_window,optional"`
+
+	// ParallelizationFactor is how many batches from each shard are
+	// processed at once, between 1 and 10.
+	ParallelizationFactor *int64 `hcl:"parallelization_factor,optional"`
+
+	// BisectBatchOnError splits a failed batch in two and retries each half.
+	BisectBatchOnError bool `hcl:"bisect_batch_on_error,optional"`
+
+	// MaxRecordAge and MaxRetryAttempts bound how long and how often a
+	// failed batch is retried, forever by default.
+	MaxRecordAge     string `hcl:"max_record_age,optional"`
+	MaxRetryAttempts *int64 `hcl:"max_retry_attempts,optional"`
+
+	// OnFailureDestination is an SQS queue or SNS topic ARN receiving the
+	// details of batches that are given up on.
+	OnFailureDestination string `hcl:"on_failure_destination,optional"`
+
+	// TumblingWindow groups records into windows of this length, such as
+	// "1m", passing state from one invocation to the next within each.
+	TumblingWindow string `hcl:"tumbling_window,optional"`
+
+	// ReportBatchItemFailures lets the function return the first record it
+	// failed to process so that the batch is retried from there.
+	ReportBatchItemFailures bool `hcl:"report_batch_item_failures,optional"`
+
+	// Enabled is whether the stream is polled, true by default.
+	Enabled *bool `hcl:"enabled,optional"`
+}
+
+// The bounds Lambda puts on polling streams.
+const (
+	maxStreamBatchSize     = 10000
+	defaultStreamBatchSize = 100
+	maxParallelization     = 10
+	minRecordAge           = time.Minute
+	maxRecordAge           = 7 * 24 * time.Hour
+	maxStreamRetryAttempts = 10000
+	maxTumblingWindow      = 15 * time.Minute
+)
+
+func (c *StreamConfig) isKinesis() bool {
+	return strings.Contains(c.SourceArn, ":kinesis:")
+}
+
+// kind describes the stream for messages.
+func (c *StreamConfig) kind() string {
+	if c.isKinesis() {
+		return "Kinesis stream"
+	}
+	return "DynamoDB stream"
+}
+
+func (c *StreamConfig) validate() error {
+	dynamo := strings.Contains(c.SourceArn, ":dynamodb:") && strings.Contains(c.SourceArn, "/stream/")
+	if !strings.HasPrefix(c.SourceArn, "arn:") || (!c.isKinesis() && !dynamo) {
+		return fmt.Errorf("stream source_arn %q is not a Kinesis or DynamoDB stream ARN", c.SourceArn)
+	}
+
+	switch c.StartingPosition {
+	case "", lambda.EventSourcePositionLatest, lambda.EventSourcePositionTrimHorizon:
+		if c.StartingTimestamp != "" {
+			return fmt.Errorf("stream starting_timestamp requires starting_position AT_TIMESTAMP")
+		}
+	case lambda.EventSourcePositionAtTimestamp:
+		if !c.isKinesis() {
+			return fmt.Errorf("stream starting_position AT_TIMESTAMP is only supported for Kinesis")
+		}
+
+		if _, err := time.Parse(time.RFC3339, c.StartingTimestamp); err != nil {
+			return fmt.Errorf("invalid stream starting_timestamp %q: %s", c.StartingTimestamp, err)
+		}
+	default:
+		return fmt.Errorf("stream starting_position must be TRIM_HORIZON, LATEST or AT_TIMESTAMP")
+	}
+
+	if c.BatchSize != nil && (*c.BatchSize < 1 || *c.BatchSize > maxStreamBatchSize) {
+		return fmt.Errorf("stream batch_size must be between 1 and %d", maxStreamBatchSize)
+	}
+
+	if err := validateDuration("stream batching_window", c.BatchingWindow, 0, maxBatchingWindow); err != nil {
+		return err
+	}
+
+	if p := c.ParallelizationFactor; p != nil && (*p < 1 || *p > maxParallelization) {
+		return fmt.Errorf("stream parallelization_factor must be between 1 and %d", maxParallelization)
+	}
+
+	if err := validateDuration("stream max_record_age", c.MaxRecordAge, minRecordAge, maxRecordAge); err != nil {
+		return err
+	}
+
+	if r := c.MaxRetryAttempts; r != nil && (*r < 0 || *r > maxStreamRetryAttempts) {
+		return fmt.Errorf("stream max_retry_attempts must be between 0 and %d", maxStreamRetryAttempts)
+	}
+
+	if d := c.OnFailureDestination; d != "" && !strings.Contains(d, ":sqs:") && !strings.Contains(d, ":sns:") {
+		return fmt.Errorf("stream on_failure_destination %q is not an SQS queue or SNS topic ARN", d)
+	}
+
+	return validateDuration("stream tumbling_window", c.TumblingWindow, 0, maxTumblingWindow)
+}
+
+// validateDuration checks that an optional duration setting is between min
+// and max.
+func validateDuration(setting, value string, min, max time.Duration) error {
+	if value == "" {
+		return nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %s", setting, value, err)
+	}
+
+	if d < min || d > max {
+		return fmt.Errorf("%s must be between %s and %s", setting, min, max)
+	}
+
+	return nil
+}
+
+// seconds converts an optional duration setting, already validated, to
+// whole seconds.
+func seconds(value string) *int64 {
+	if value == "" {
+		return nil
+	}
+
+	d, _ := time.ParseDuration(value)
+	return aws.Int64(int64(d / time.Second))
+}
+
+// orDefault returns v, or def if v isn't set.
+func orDefault(v *int64, def int64) *int64 {
+	if v == nil {
+		return aws.Int64(def)
+	}
+	return v
+}
+
+// mappingInputs returns the requests creating or updating the mapping from
+// the stream to arn. Where a mapping starts reading can only be set when it
+// is created.
+func (c *StreamConfig) mappingInputs(arn string) (*lambda.CreateEventSourceMappingInput, *lambda.UpdateEventSourceMappingInput) {
+	enabled := true
+	if c.Enabled != nil {
+		enabled = *c.Enabled
+	}
+
+	// An empty list turns partial batch responses off on update
+	responseTypes := []*string{}
+	if c.ReportBatchItemFailures {
+		responseTypes = aws.StringSlice([]string{lambda.FunctionResponseTypeReportBatchItemFailures})
+	}
+
+	// An empty destination removes the one set before
+	destination := &lambda.DestinationConfig{
+		OnFailure: &lambda.OnFailure{Destination: aws.String(c.OnFailureDestination)},
+	}
+
+	position := c.StartingPosition
+	if position == "" {
+		position = lambda.EventSourcePositionLatest
+	}
+
+	create := &lambda.CreateEventSourceMappingInput{
+		EventSourceArn:                 aws.String(c.SourceArn),
+		FunctionName:                   aws.String(arn),
+		StartingPosition:               aws.String(position),
+		BatchSize:                      c.BatchSize,
+		MaximumBatchingWindowInSeconds: seconds(c.BatchingWindow),
+		ParallelizationFactor:          c.ParallelizationFactor,
+		BisectBatchOnFunctionError:     aws.Bool(c.BisectBatchOnError),
+		MaximumRecordAgeInSeconds:      seconds(c.MaxRecordAge),
+		MaximumRetryAttempts:           c.MaxRetryAttempts,
+		TumblingWindowInSeconds:        seconds(c.TumblingWindow),
+		FunctionResponseTypes:          responseTypes,
+		Enabled:                        aws.Bool(enabled),
+	}
+
+	if position == lambda.EventSourcePositionAtTimestamp {
+		t, _ := time.Parse(time.RFC3339, c.StartingTimestamp)
+		create.StartingPositionTimestamp = aws.Time(t)
+	}
+
+	if c.OnFailureDestination != "" {
+		create.DestinationConfig = destination
+	}
+
+	// Settings removed from the config are reset to Lambda's defaults on
+	// update, -1 meaning no limit on retries
+	update := &lambda.UpdateEventSourceMappingInput{
+		BatchSize:                      orDefault(c.BatchSize, defaultStreamBatchSize),
+		MaximumBatchingWindowInSeconds: orDefault(seconds(c.BatchingWindow), 0),
+		ParallelizationFactor:          orDefault(c.ParallelizationFactor, 1),
+		BisectBatchOnFunctionError:     aws.Bool(c.BisectBatchOnError),
+		MaximumRecordAgeInSeconds:      orDefault(seconds(c.MaxRecordAge), -1),
+		MaximumRetryAttempts:           orDefault(c.MaxRetryAttempts, -1),
+		TumblingWindowInSeconds:        orDefault(seconds(c.TumblingWindow), 0),
+		FunctionResponseTypes:          responseTypes,
+		DestinationConfig:              destination,
+		Enabled:                        aws.Bool(enabled),
+	}
+
+	return create, update
+}
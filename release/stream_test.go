@@ -0,0 +1,60 @@
+package release
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestStreamValidate(t *testing.T) {
+	const kinesis = "arn:aws:kinesis:eu-west-1:123456789012:stream/orders"
+	const dynamo = "arn:aws:dynamodb:eu-west-1:123456789012:table/orders/stream/2026-10-16T00:00:00.000"
+
+	cases := []struct {
+		name   string
+		config StreamConfig
+		valid  bool
+	}{
+		{"kinesis", StreamConfig{SourceArn: kinesis}, true},
+		{"dynamodb", StreamConfig{SourceArn: dynamo, StartingPosition: "TRIM_HORIZON"}, true},
+		{"at timestamp", StreamConfig{SourceArn: kinesis, StartingPosition: "AT_TIMESTAMP", StartingTimestamp: "2026-10-16T12:00:00Z"}, true},
+		{"limits", StreamConfig{
+			SourceArn:             kinesis,
+			BatchSize:             aws.Int64(10000),
+			BatchingWindow:        "5m",
+			ParallelizationFactor: aws.Int64(10),
+			MaxRecordAge:          "168h",
+			MaxRetryAttempts:      aws.Int64(0),
+			TumblingWindow:        "15m",
+		}, true},
+		{"sns destination", StreamConfig{SourceArn: dynamo, OnFailureDestination: "arn:aws:sns:eu-west-1:123456789012:failed"}, true},
+
+		{"not a stream", StreamConfig{SourceArn: "arn:aws:dynamodb:eu-west-1:123456789012:table/orders"}, false},
+		{"not an arn", StreamConfig{SourceArn: "orders"}, false},
+		{"unknown position", StreamConfig{SourceArn: kinesis, StartingPosition: "EARLIEST"}, false},
+		{"timestamp without position", StreamConfig{SourceArn: kinesis, StartingTimestamp: "2026-10-16T12:00:00Z"}, false},
+		{"dynamodb at timestamp", StreamConfig{SourceArn: dynamo, StartingPosition: "AT_TIMESTAMP", StartingTimestamp: "2026-10-16T12:00:00Z"}, false},
+		{"invalid timestamp", StreamConfig{SourceArn: kinesis, StartingPosition: "AT_TIMESTAMP", StartingTimestamp: "yesterday"}, false},
+		{"batch size too large", StreamConfig{SourceArn: kinesis, BatchSize: aws.Int64(10001)}, false},
+		{"window too long", StreamConfig{SourceArn: kinesis, BatchingWindow: "6m"}, false},
+		{"parallelization too high", StreamConfig{SourceArn: kinesis, ParallelizationFactor: aws.Int64(11)}, false},
+		{"record age too short", StreamConfig{SourceArn: kinesis, MaxRecordAge: "30s"}, false},
+		{"record age too long", StreamConfig{SourceArn: kinesis, MaxRecordAge: "169h"}, false},
+		{"negative retries", StreamConfig{SourceArn: kinesis, MaxRetryAttempts: aws.Int64(-1)}, false},
+		{"too many retries", StreamConfig{SourceArn: kinesis, MaxRetryAttempts: aws.Int64(10001)}, false},
+		{"destination not a queue or topic", StreamConfig{SourceArn: kinesis, OnFailureDestination: "arn:aws:s3:::failed"}, false},
+		{"tumbling window too long", StreamConfig{SourceArn: kinesis, TumblingWindow: "16m"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.config.validate()
+			if c.valid && err != nil {
+				t.Errorf("validate: %s", err)
+			}
+			if !c.valid && err == nil {
+				t.Errorf("validate succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+// Package schedule parses EventBridge schedule expressions, cron(...) and
+// rate(...), and works out when they fire.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed schedule expression.
+type Schedule interface {
+	// Next returns the first fire time after t, or the zero time if there
+	// is none. Times are in UTC, as EventBridge evaluates them.
+	Next(t time.Time) time.Time
+}
+
+// Parse parses a cron(...) or rate(...) expression.
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+
+	switch {
+	case strings.HasPrefix(expr, "rate(") && strings.HasSuffix(expr, ")"):
+		return parseRate(expr[len("rate(") : len(expr)-1])
+	case strings.HasPrefix(expr, "cron(") && strings.HasSuffix(expr, ")"):
+		return parseCron(expr[len("cron(") : len(expr)-1])
+	}
+
+	return nil, fmt.Errorf("schedule %q must be cron(...) or rate(...)", expr)
+}
+
+// NextN returns the next n fire times after t.
+func NextN(s Schedule, t time.Time, n int) []time.Time {
+	var times []time.Time
+
+	for len(times) < n {
+		t = s.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+
+	return times
+}
+
+// Rate fires at a fixed interval. EventBridge counts it from when the rule
+// is created, so Next is only an estimate.
+type Rate struct {
+	Interval time.Duration
+}
+
+func parseRate(s string) (Schedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("rate must be \"rate(<value> <unit>)\"")
+	}
+
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("rate value %q must be a positive whole number", fields[0])
+	}
+
+	units := map[string]time.Duration{
+		"minute": time.Minute,
+		"hour":   time.Hour,
+		"day":    24 * time.Hour,
+	}
+
+	unit := fields[1]
+	if n == 1 {
+		if _, ok := units[unit]; !ok {
+			return nil, fmt.Errorf("rate unit %q must be minute, hour or day for a value of 1", unit)
+		}
+	} else {
+		if !strings.HasSuffix(unit, "s") || units[strings.TrimSuffix(unit, "s")] == 0 {
+			return nil, fmt.Errorf("rate unit %q must be minutes, hours or days", unit)
+		}
+		unit = strings.TrimSuffix(unit, "s")
+	}
+
+	return &Rate{Interval: time.Duration(n) * units[unit]}, nil
+}
+
+func (r *Rate) Next(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Minute).Add(r.Interval)
+}
+
+// Cron fires at the times matching all of its fields.
+type Cron struct {
+	minutes, hours, months, years map[int]bool
+
+	// The day of month and day of week fields, one of which is always "?".
+	dom, dow dayField
+}
+
+// The range of years EventBridge accepts.
+const (
+	minYear = 1970
+	maxYear = 2199
+)
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+// Days of the week are numbered from Sunday, as 1.
+var dayNames = map[string]int{
+	"SUN": 1, "MON": 2, "TUE": 3, "WED": 4, "THU": 5, "FRI": 6, "SAT": 7,
+}
+
+func parseCron(s string) (Schedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("cron must have 6 fields: minutes hours day-of-month month day-of-week year")
+	}
+
+	c := &Cron{}
+
+	var err error
+	if c.minutes, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("cron minutes: %s", err)
+	}
+	if c.hours, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("cron hours: %s", err)
+	}
+	if c.months, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("cron month: %s", err)
+	}
+	if c.years, err = parseField(fields[5], minYear, maxYear, nil); err != nil {
+		return nil, fmt.Errorf("cron year: %s", err)
+	}
+
+	if (fields[2] == "?") == (fields[4] == "?") {
+		return nil, fmt.Errorf("cron must have \"?\" in exactly one of day-of-month and day-of-week")
+	}
+
+	if c.dom, err = parseDayOfMonth(fields[2]); err != nil {
+		return nil, fmt.Errorf("cron day-of-month: %s", err)
+	}
+	if c.dow, err = parseDayOfWeek(fields[4]); err != nil {
+		return nil, fmt.Errorf("cron day-of-week: %s", err)
+	}
+
+	return c, nil
+}
+
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+
+	for t.Year() <= maxYear {
+		switch {
+		case !c.years[t.Year()]:
+			t = time.Date(t.Year()+1, 1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.months[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.dom.match(t) || !c.dow.match(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case !c.hours[t.Hour()]:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case !c.minutes[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// parseField parses a comma separated list of values, ranges and steps, such
+// as "*", "0/15", "1-5" or "MON,WED".
+func parseField(s string, min, max int, names map[string]int) (map[int]bool, error) {
+	set := map[int]bool{}
+
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, step := min, max, 1
+
+		base := part
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+			base = part[:i]
+		}
+
+		switch {
+		case base == "*":
+		case strings.Contains(base, "-"):
+			i := strings.Index(base, "-")
+
+			var err error
+			if lo, err = parseValue(base[:i], min, max, names); err != nil {
+				return nil, err
+			}
+			if hi, err = parseValue(base[i+1:], min, max, names); err != nil {
+				return nil, err
+			}
+			if lo > hi {
+				return nil, fmt.Errorf("invalid range %q", base)
+			}
+		default:
+			v, err := parseValue(base, min, max, names)
+			if err != nil {
+				return nil, err
+			}
+
+			// "5/10" starts at 5 and steps to the end of the range
+			lo = v
+			if step == 1 {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+
+	return set, nil
+}
+
+func parseValue(s string, min, max int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+
+	if v < min || v > max {
+		return 0, fmt.Errorf("%d is outside %d-%d", v, min, max)
+	}
+
+	return v, nil
+}
+
+// dayField matches days of the month or week, including the special forms
+// of each.
+type dayField interface {
+	match(t time.Time) bool
+}
+
+// anyDay is "?".
+type anyDay struct{}
+
+func (anyDay) match(time.Time) bool { return true }
+
+// daySet matches days in a set, of the month or of the week.
+type daySet struct {
+	days    map[int]bool
+	weekday bool
+}
+
+func (d daySet) match(t time.Time) bool {
+	if d.weekday {
+		return d.days[int(t.Weekday())+1]
+	}
+	return d.days[t.Day()]
+}
+
+// lastDay is "L", the last day of the month, or "LW", its last weekday.
+type lastDay struct {
+	weekday bool
+}
+
+func (d lastDay) match(t time.Time) bool {
+	last := daysIn(t)
+	if d.weekday {
+		return t.Day() == nearestWeekday(t, last)
+	}
+	return t.Day() == last
+}
+
+// nearestWeekdayTo is "<n>W", the weekday closest to the nth of the month.
+type nearestWeekdayTo int
+
+func (d nearestWeekdayTo) match(t time.Time) bool {
+	n := int(d)
+	if n > daysIn(t) {
+		return false
+	}
+	return t.Day() == nearestWeekday(t, n)
+}
+
+// lastWeekday is "<d>L", the last given day of the week in the month.
+type lastWeekday int
+
+func (d lastWeekday) match(t time.Time) bool {
+	return int(t.Weekday())+1 == int(d) && t.Day()+7 > daysIn(t)
+}
+
+// nthWeekday is "<d>#<n>", the nth given day of the week in the month.
+type nthWeekday struct {
+	day, n int
+}
+
+func (d nthWeekday) match(t time.Time) bool {
+	return int(t.Weekday())+1 == d.day && (t.Day()-1)/7+1 == d.n
+}
+
+func parseDayOfMonth(s string) (dayField, error) {
+	switch {
+	case s == "?":
+		return anyDay{}, nil
+	case s == "L":
+		return lastDay{}, nil
+	case s == "LW":
+		return lastDay{weekday: true}, nil
+	case strings.HasSuffix(s, "W"):
+		n, err := parseValue(strings.TrimSuffix(s, "W"), 1, 31, nil)
+		if err != nil {
+			return nil, err
+		}
+		return nearestWeekdayTo(n), nil
+	}
+
+	days, err := parseField(s, 1, 31, nil)
+	if err != nil {
+		return nil, err
+	}
+	return daySet{days: days}, nil
+}
+
+func parseDayOfWeek(s string) (dayField, error) {
+	switch {
+	case s == "?":
+		return anyDay{}, nil
+	case strings.Contains(s, "#"):
+		i := strings.Index(s, "#")
+
+		day, err := parseValue(s[:i], 1, 7, dayNames)
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := parseValue(s[i+1:], 1, 5, nil)
+		if err != nil {
+			return nil, err
+		}
+		return nthWeekday{day: day, n: n}, nil
+	case len(s) > 1 && strings.HasSuffix(s, "L"):
+		day, err := parseValue(strings.TrimSuffix(s, "L"), 1, 7, dayNames)
+		if err != nil {
+			return nil, err
+		}
+		return lastWeekday(day), nil
+	}
+
+	days, err := parseField(s, 1, 7, dayNames)
+	if err != nil {
+		return nil, err
+	}
+	return daySet{days: days, weekday: true}, nil
+}
+
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// nearestWeekday returns the weekday in t's month closest to day n, without
+// crossing into another month.
+func nearestWeekday(t time.Time, n int) int {
+	d := time.Date(t.Year(), t.Month(), n, 0, 0, 0, 0, time.UTC)
+
+	switch d.Weekday() {
+	case time.Saturday:
+		if n == 1 {
+			return n + 2
+		}
+		return n - 1
+	case time.Sunday:
+		if n == daysIn(t) {
+			return n - 2
+		}
+		return n + 1
+	}
+
+	return n
+}
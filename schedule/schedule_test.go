@@ -0,0 +1,136 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		expr  string
+		valid bool
+	}{
+		{"rate(1 minute)", true},
+		{"rate(5 minutes)", true},
+		{"rate(1 hour)", true},
+		{"rate(7 days)", true},
+		{"cron(0 12 * * ? *)", true},
+		{"cron(0/15 9-17/2 ? * MON-FRI *)", true},
+		{"cron(0 8 1,15 JAN,JUL ? 2030-2035)", true},
+		{"cron(0 0 L * ? *)", true},
+		{"cron(0 0 LW * ? *)", true},
+		{"cron(0 0 15W * ? *)", true},
+		{"cron(0 0 ? * 6L *)", true},
+		{"cron(0 0 ? * FRI#3 *)", true},
+		{" cron(0 12 * * ? *) ", true},
+
+		{"rate(1 minutes)", false},
+		{"rate(5 minute)", false},
+		{"rate(0 minutes)", false},
+		{"rate(-1 hours)", false},
+		{"rate(1.5 hours)", false},
+		{"rate(5 weeks)", false},
+		{"rate(5)", false},
+		{"every 5 minutes", false},
+		{"cron(0 12 * * *)", false},
+		{"cron(0 12 * * ? * *)", false},
+		{"cron(0 12 * * * *)", false},
+		{"cron(0 12 ? * ? *)", false},
+		{"cron(60 12 * * ? *)", false},
+		{"cron(0 24 * * ? *)", false},
+		{"cron(0 12 32 * ? *)", false},
+		{"cron(0 12 * 13 ? *)", false},
+		{"cron(0 12 ? * 8 *)", false},
+		{"cron(0 12 * * ? 2200)", false},
+		{"cron(0 12 * * ? 1969)", false},
+		{"cron(30-10 12 * * ? *)", false},
+		{"cron(0/0 12 * * ? *)", false},
+		{"cron(0 12 32W * ? *)", false},
+		{"cron(0 12 ? * 6#6 *)", false},
+		{"cron(0 12 ? * 8L *)", false},
+		{"cron(0 12 ? * FOO *)", false},
+	}
+
+	for _, c := range cases {
+		_, err := Parse(c.expr)
+		if c.valid && err != nil {
+			t.Errorf("Parse(%q): %s", c.expr, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", c.expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(s string) time.Time {
+		t, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+
+	cases := []struct {
+		expr string
+		from string
+		want []string
+	}{
+		{"rate(5 minutes)", "2026-10-16 09:02", []string{"2026-10-16 09:07", "2026-10-16 09:12"}},
+		{"cron(0 12 * * ? *)", "2026-10-16 12:00", []string{"2026-10-17 12:00", "2026-10-18 12:00"}},
+
+		// Ranges with steps
+		{"cron(0/20 9-17/4 * * ? *)", "2026-10-16 08:00", []string{
+			"2026-10-16 09:00", "2026-10-16 09:20", "2026-10-16 09:40", "2026-10-16 13:00",
+		}},
+		{"cron(45 10 ? * MON-FRI *)", "2026-10-16 11:00", []string{"2026-10-19 10:45", "2026-10-20 10:45"}},
+
+		// The third and the last Friday of the month
+		{"cron(0 12 ? * 6#3 *)", "2026-10-01 00:00", []string{"2026-10-16 12:00", "2026-11-20 12:00"}},
+		{"cron(0 12 ? * 6L *)", "2026-10-01 00:00", []string{"2026-10-30 12:00", "2026-11-27 12:00"}},
+
+		// The last day of the month, including a leap February
+		{"cron(0 12 L * ? *)", "2028-01-31 12:00", []string{"2028-02-29 12:00", "2028-03-31 12:00"}},
+
+		// The weekday nearest the 1st, a Saturday, and the last weekday of a
+		// month ending on a Saturday
+		{"cron(0 0 1W * ? *)", "2026-07-31 00:00", []string{"2026-08-03 00:00"}},
+		{"cron(0 0 LW * ? *)", "2026-10-01 00:00", []string{"2026-10-30 00:00"}},
+
+		// The year field
+		{"cron(0 0 1 1 ? 2030,2032)", "2026-10-16 00:00", []string{"2030-01-01 00:00", "2032-01-01 00:00"}},
+		{"cron(0 0 1 1 ? 2020)", "2026-10-16 00:00", nil},
+	}
+
+	for _, c := range cases {
+		s, err := Parse(c.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %s", c.expr, err)
+		}
+
+		// When no times are expected, ask for one to check there is none
+		n := len(c.want)
+		if n == 0 {
+			n = 1
+		}
+
+		got := NextN(s, at(c.from), n)
+
+		var want []time.Time
+		for _, w := range c.want {
+			want = append(want, at(w))
+		}
+
+		if len(got) != len(want) {
+			t.Errorf("%s after %s: got %v, want %v", c.expr, c.from, got, want)
+			continue
+		}
+
+		for i := range want {
+			if !got[i].Equal(want[i]) {
+				t.Errorf("%s after %s: got %v, want %v", c.expr, c.from, got, want)
+				break
+			}
+		}
+	}
+}